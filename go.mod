@@ -2,7 +2,10 @@ module github.com/kubesys/client-go
 
 go 1.21.5
 
-require github.com/tidwall/gjson v1.14.0
+require (
+	github.com/tidwall/gjson v1.14.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/tidwall/match v1.1.1 // indirect
@@ -10,7 +10,7 @@ import (
 
 func main() {
 
-	fmt.Println("default token is /etc/kubernetes/admin.conf on Master")
+	fmt.Println("default kubeconfig is $KUBECONFIG or $HOME/.kube/config, or the in-cluster service account")
 	//client := kubesys.NewKubernetesClientWithKubeConfig(".token")
 	//client.Init()
 	client := kubesys.NewKubernetesClientWithDefaultKubeConfig()
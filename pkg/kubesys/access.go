@@ -0,0 +1,134 @@
+/**
+ * Copyright (2021, ) Institute of Software, Chinese Academy of Sciences
+ */
+
+package kubesys
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+/**
+ * this class is used for checking the permissions of the current credentials,
+ * like 'kubectl auth can-i', see
+ * https://kubernetes.io/docs/reference/access-authn-authz/authorization/#checking-api-access
+ *
+ *      date  : 2026/10/18
+ *      since : v2.0.4
+ */
+
+const (
+	selfSubjectAccessReviewUrl = "/apis/authorization.k8s.io/v1/selfsubjectaccessreviews"
+	selfSubjectRulesReviewUrl  = "/apis/authorization.k8s.io/v1/selfsubjectrulesreviews"
+)
+
+type ResourceRule struct {
+	Verbs         []string `json:"verbs"`
+	APIGroups     []string `json:"apiGroups,omitempty"`
+	Resources     []string `json:"resources,omitempty"`
+	ResourceNames []string `json:"resourceNames,omitempty"`
+}
+
+type NonResourceRule struct {
+	Verbs           []string `json:"verbs"`
+	NonResourceURLs []string `json:"nonResourceURLs,omitempty"`
+}
+
+// SubjectRules may be Incomplete if an authorizer cannot list rules, such as webhooks,
+// then a request not covered by the rules may still be allowed
+type SubjectRules struct {
+	ResourceRules    []ResourceRule    `json:"resourceRules"`
+	NonResourceRules []NonResourceRule `json:"nonResourceRules"`
+	Incomplete       bool              `json:"incomplete"`
+	EvaluationError  string            `json:"evaluationError,omitempty"`
+}
+
+// CanI reports whether the current credentials allow the verb on the kind, such as
+// CanI("delete", "Pod", "default", "busybox"). The kind is resolved through RuleBase,
+// an empty name means all objects, and the namespace is ignored for cluster-scoped kinds
+func (client *KubernetesClient) CanI(verb string, kind string, namespace string, name string) (bool, error) {
+	return client.CanIWithContext(context.Background(), verb, kind, namespace, name)
+}
+
+func (client *KubernetesClient) CanIWithContext(ctx context.Context, verb string, kind string, namespace string, name string) (bool, error) {
+	ruleBase := client.analyzer.RuleBase
+	fullKind, err := toFullKind(kind, ruleBase.KindToFullKindMapper)
+	if err != nil {
+		return false, err
+	}
+	resource, ok := ruleBase.FullKindToNameMapper[fullKind]
+	if !ok {
+		return false, fmt.Errorf("wrong fullKind %s, please invoking 'GetFullKinds'", fullKind)
+	}
+	if !ruleBase.FullKindToNamespaceMapper[fullKind] {
+		namespace = ""
+	}
+
+	attributes := map[string]interface{}{
+		"verb":     verb,
+		"group":    ruleBase.FullKindToGroupMapper[fullKind],
+		"resource": resource,
+	}
+	if len(namespace) != 0 {
+		attributes["namespace"] = namespace
+	}
+	if len(name) != 0 {
+		attributes["name"] = name
+	}
+
+	var review struct {
+		Status struct {
+			Allowed bool `json:"allowed"`
+		} `json:"status"`
+	}
+	if err := client.createReview(ctx, selfSubjectAccessReviewUrl, "SelfSubjectAccessReview",
+		map[string]interface{}{"resourceAttributes": attributes}, &review); err != nil {
+		return false, err
+	}
+	return review.Status.Allowed, nil
+}
+
+// ListRules returns all the rules allowed for the current credentials in the namespace,
+// like 'kubectl auth can-i --list'
+func (client *KubernetesClient) ListRules(namespace string) (*SubjectRules, error) {
+	return client.ListRulesWithContext(context.Background(), namespace)
+}
+
+func (client *KubernetesClient) ListRulesWithContext(ctx context.Context, namespace string) (*SubjectRules, error) {
+	namespace = client.namespaceOrDefault(namespace)
+
+	var review struct {
+		Status SubjectRules `json:"status"`
+	}
+	if err := client.createReview(ctx, selfSubjectRulesReviewUrl, "SelfSubjectRulesReview",
+		map[string]interface{}{"namespace": namespace}, &review); err != nil {
+		return nil, err
+	}
+	return &review.Status, nil
+}
+
+// createReview posts the review, and decodes the response with status into value
+func (client *KubernetesClient) createReview(ctx context.Context, url string, kind string, spec map[string]interface{}, value interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"apiVersion": "authorization.k8s.io/v1",
+		"kind":       kind,
+		"spec":       spec,
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx = withRequestInfo(ctx, RequestInfo{Verb: "create", FullKind: "authorization.k8s.io." + kind})
+	req, err := client.createRequest(ctx, "POST", client.Url+url, strings.NewReader(string(body)))
+	if err != nil {
+		return err
+	}
+	res, err := client.doRequest(req)
+	if err != nil {
+		return fmt.Errorf("creating %s: %w", kind, err)
+	}
+	return json.Unmarshal(res, value)
+}
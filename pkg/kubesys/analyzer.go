@@ -5,6 +5,7 @@
 package kubesys
 
 import (
+	"context"
 	"strings"
 )
 
@@ -37,9 +38,13 @@ func NewKubernetesAnalyzer() *KubernetesAnalyzer {
 	return analyzer
 }
 
-func (analyzer *KubernetesAnalyzer) Learning(client *KubernetesClient) {
-	extract(client, analyzer.Registry)
-	//listen(client, analyzer.Registry)
+func (analyzer *KubernetesAnalyzer) Learning(client *KubernetesClient) error {
+	return analyzer.LearningWithContext(context.Background(), client)
+}
+
+func (analyzer *KubernetesAnalyzer) LearningWithContext(ctx context.Context, client *KubernetesClient) error {
+	return extract(ctx, client, analyzer.Registry)
+	//listen(ctx, client, analyzer.Registry)
 }
 
 func getGroup(apiVersion string) string {
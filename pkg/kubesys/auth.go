@@ -0,0 +1,125 @@
+/**
+ * Copyright (2021, ) Institute of Software, Chinese Academy of Sciences
+ */
+
+package kubesys
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http"
+)
+
+/**
+ * this class is used for adding the credentials of kubeconfig users
+ * to the requests, the credential set by the caller is kept
+ *
+ *      date  : 2026/10/18
+ *      since : v2.0.4
+ */
+
+func authRoundTripperFor(config *Config, base http.RoundTripper) (http.RoundTripper, error) {
+	hasToken := len(config.Token) != 0 || len(config.TokenFile) != 0
+	hasBasic := len(config.Username) != 0 || len(config.Password) != 0
+	if hasToken && hasBasic {
+		return nil, errors.New("username/password or bearer token may be set, but not both")
+	}
+
+	if config.AuthProvider != nil {
+		if hasToken || hasBasic || config.Exec != nil {
+			return nil, errors.New("auth-provider cannot be used in combination with a token, username/password or exec plugin")
+		}
+		authenticator, err := newOIDCAuthenticator(config)
+		if err != nil {
+			return nil, err
+		}
+		return &bearerAuthRoundTripper{source: authenticator, base: base}, nil
+	}
+
+	if config.Exec != nil {
+		if hasToken || hasBasic {
+			return nil, errors.New("exec plugin cannot be used in combination with a token or username/password")
+		}
+		authenticator, err := newExecAuthenticator(config)
+		if err != nil {
+			return nil, err
+		}
+		// like kubectl, the static client certificate of the user takes precedence, otherwise
+		// Go would ignore it for the hook, and send no certificate if the plugin returns a token
+		hasCertificate := len(config.ClientCertificate) != 0 || len(config.ClientCertificateData) != 0 ||
+			len(config.ClientKey) != 0 || len(config.ClientKeyData) != 0
+		if transport, ok := base.(*http.Transport); ok && !hasCertificate {
+			// the hook is set on a copy, since the transport may be given by WithTransport and shared
+			transport = transport.Clone()
+			if transport.TLSClientConfig == nil {
+				transport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+			}
+			authenticator.onRotate = transport.CloseIdleConnections
+			transport.TLSClientConfig.GetClientCertificate = authenticator.getClientCertificate
+			base = transport
+		}
+		return &execRoundTripper{authenticator: authenticator, base: base}, nil
+	}
+
+	if len(config.TokenFile) != 0 {
+		// like kubectl, the rotated token file takes precedence, and the token
+		// is only used until the file can be read
+		source := newFileTokenSource(config.TokenFile)
+		source.token = config.Token
+		if _, err := source.Token(); err != nil {
+			return nil, err
+		}
+		return &bearerAuthRoundTripper{source: source, base: base}, nil
+	}
+
+	if len(config.Token) != 0 {
+		return &bearerAuthRoundTripper{source: &staticTokenSource{token: config.Token}, base: base}, nil
+	}
+
+	if hasBasic {
+		return &basicAuthRoundTripper{username: config.Username, password: config.Password, base: base}, nil
+	}
+	return base, nil
+}
+
+type bearerAuthRoundTripper struct {
+	source TokenSource
+	base   http.RoundTripper
+}
+
+func (rt *bearerAuthRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if len(req.Header.Get("Authorization")) != 0 {
+		return rt.base.RoundTrip(req)
+	}
+
+	token, err := rt.source.Token()
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	res, err := rt.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if source, ok := rt.source.(invalidator); ok && res.StatusCode == http.StatusUnauthorized {
+		source.invalidate()
+	}
+	return res, nil
+}
+
+type basicAuthRoundTripper struct {
+	username string
+	password string
+	base     http.RoundTripper
+}
+
+func (rt *basicAuthRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if len(req.Header.Get("Authorization")) != 0 {
+		return rt.base.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	req.SetBasicAuth(rt.username, rt.password)
+	return rt.base.RoundTrip(req)
+}
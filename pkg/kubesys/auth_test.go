@@ -0,0 +1,53 @@
+/**
+ * Copyright (2021, ) Institute of Software, Chinese Academy of Sciences
+ */
+
+package kubesys
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type recordingRoundTripper struct {
+	authorization string
+}
+
+func (rt *recordingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.authorization = req.Header.Get("Authorization")
+	return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+}
+
+func TestTokenFileTakesPrecedence(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("rotated\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		tokenFile string
+		expected  string
+	}{
+		{name: "token file", tokenFile: tokenFile, expected: "Bearer rotated"},
+		{name: "missing token file", tokenFile: tokenFile + ".missing", expected: "Bearer static"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			base := new(recordingRoundTripper)
+			rt, err := authRoundTripperFor(&Config{Token: "static", TokenFile: test.tokenFile}, base)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req, _ := http.NewRequest("GET", "https://127.0.0.1:6443/api", nil)
+			if _, err := rt.RoundTrip(req); err != nil {
+				t.Fatal(err)
+			}
+			if base.authorization != test.expected {
+				t.Fatalf("expected %q, got %q", test.expected, base.authorization)
+			}
+		})
+	}
+}
@@ -5,22 +5,22 @@
 package kubesys
 
 import (
-	"crypto/tls"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/tidwall/gjson"
 	"io"
 	"io/ioutil"
-	"net"
 	"net/http"
-	"os"
 	"strings"
+	"time"
 )
 
 /**
  * this class is used for creating a connection between users' application and Kubernetes server.
  * It provides an easy-to-use way to Create, Update, Delete, Get, List and Watch all Kubernetes resources.
+ * Every operation has a ...WithContext variant, whose context cancels the request or the watch.
  *
  *      author: wuheng@iscas.ac.cn
  *      date  : 2022/4/1
@@ -34,10 +34,21 @@ import (
  *************************************************************/
 
 type KubernetesClient struct {
-	Url      string              // required, user input
-	Token    string              // required, user input
-	http     *http.Client        // required, automatically created based on Url and Token
-	analyzer *KubernetesAnalyzer // required, user input or automatically register all Kubernetes resources based on Http
+	Url          string               // required, user input
+	Token        string               // required, user input
+	Namespace    string               // optional, the default namespace of the operations on one object, see objectNamespace
+	tokens       TokenSource          // optional, reloads the rotated token instead of using Token
+	userAgent    string               // optional, the User-Agent header of every request
+	timeout      time.Duration        // optional, the timeout of every request except watches
+	limiter      *rateLimiter         // optional, limits the requests per second
+	retry        *RetryPolicy         // optional, retries the requests failed with transient errors
+	impersonate  *ImpersonationConfig // optional, acts as another user
+	interceptors []Interceptor        // optional, sees every request and its response
+	metrics      *Metrics             // required, automatically created or shared by user input
+	tracer       Tracer               // optional, starts a span around every request
+	cluster      *Config              // optional, how the server is reached, embedded in the issued Configs, nil for unix sockets
+	http         *http.Client         // required, automatically created based on Url and Token
+	analyzer     *KubernetesAnalyzer  // required, user input or automatically register all Kubernetes resources based on Http
 }
 
 /************************************************************
@@ -51,11 +62,11 @@ func createClient(url string, token string, http *http.Client, analyzer *Kuberne
 	client := new(KubernetesClient)
 
 	// assignment
-	client.Url = checkedUrl(url)
-	//client.Token = checkedToken(token)
+	client.Url = url
 	client.Token = token
 	client.http = http
 	client.analyzer = analyzer
+	client.metrics = NewMetrics()
 
 	// return
 	return client
@@ -65,65 +76,112 @@ func createClient(url string, token string, http *http.Client, analyzer *Kuberne
 // gives to pods. It's intended for clients that expect to be
 // running inside a pod running on kubernetes.
 func NewKubernetesClientInCluster() *KubernetesClient {
-	const (
-		tokenFile  = "/var/run/secrets/kubernetes.io/serviceaccount/token"
-		rootCAFile = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
-	)
-	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
-	if len(host) == 0 || len(port) == 0 {
-		panic("unable to load in-cluster configuration, KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT must be defined\"")
-	}
+	return mustClient(TryNewKubernetesClientInCluster())
+}
 
-	token, err := os.ReadFile(tokenFile)
-	if err != nil {
-		panic(err)
-	}
-	return NewKubernetesClient("https://"+net.JoinHostPort(host, port), checkedToken(string(token)))
+// NewKubernetesClient verifies the server certificate with the given PEM encoded CA bundles,
+// or with the system CAs if no bundle is given
+func NewKubernetesClient(url string, token string, caData ...[]byte) *KubernetesClient {
+	return mustClient(TryNewKubernetesClient(url, token, caData...))
 }
 
-func NewKubernetesClient(url string, token string) *KubernetesClient {
-	return createClient(url, checkedToken(token), &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: true},
-		}}, NewKubernetesAnalyzer())
+// NewKubernetesClientInsecure does not verify the server certificate, which is
+// vulnerable to man-in-the-middle attacks, and should only be used for testing
+func NewKubernetesClientInsecure(url string, token string) *KubernetesClient {
+	return mustClient(TryNewKubernetesClientInsecure(url, token))
 }
 
-// NewKubernetesClientWithDefaultKubeConfig TODO
+// NewKubernetesClientWithDefaultKubeConfig follows kubectl's rules to find the kubeConfig,
+// the files in KUBECONFIG are merged in order, or else $HOME/.kube/config is used.
+// If none of them exists, it falls back to NewKubernetesClientInCluster
 func NewKubernetesClientWithDefaultKubeConfig() *KubernetesClient {
-	// filepath.Join(os.Getenv("HOME"), ".kube", "config")
-	return NewKubernetesClientWithKubeConfig("/etc/kubernetes/admin.conf")
+	return mustClient(TryNewKubernetesClientWithDefaultKubeConfig())
 }
 
-// NewKubernetesClientWithKubeConfig TODO
+// NewKubernetesClientWithKubeConfig returns a kubernetesClient for the current-context of the kubeConfig file
 func NewKubernetesClientWithKubeConfig(kubeConfig string) *KubernetesClient {
-	config, err := NewForConfig(kubeConfig)
-	if err != nil {
-		panic(err)
-	}
+	return mustClient(TryNewKubernetesClientWithKubeConfig(kubeConfig))
+}
 
-	httpClient, err := HTTPClientFor(config)
+// NewKubernetesClientWithKubeConfigContext returns a kubernetesClient for the named context of
+// the kubeConfig file, an empty contextName means the current-context
+func NewKubernetesClientWithKubeConfigContext(kubeConfig string, contextName string) *KubernetesClient {
+	return mustClient(TryNewKubernetesClientWithKubeConfigContext(kubeConfig, contextName))
+}
+
+func NewKubernetesClientWithAnalyzer(url string, token string, analyzer *KubernetesAnalyzer, caData ...[]byte) *KubernetesClient {
+	return mustClient(TryNewKubernetesClientWithAnalyzer(url, token, analyzer, caData...))
+}
+
+func mustClient(client *KubernetesClient, err error) *KubernetesClient {
 	if err != nil {
 		panic(err)
 	}
+	return client
+}
 
-	return createClient(config.Server, "", httpClient, NewKubernetesAnalyzer())
+/************************************************************
+ *
+ *      initialization without panic
+ *
+ *************************************************************/
+
+// TryNewKubernetesClientInCluster is the same as NewKubernetesClientInCluster, but returns an error instead of panic
+func TryNewKubernetesClientInCluster() (*KubernetesClient, error) {
+	return NewClient(WithInCluster())
+}
+
+// TryNewKubernetesClient is the same as NewKubernetesClient, but returns an error instead of panic
+func TryNewKubernetesClient(url string, token string, caData ...[]byte) (*KubernetesClient, error) {
+	return NewClient(WithUrl(url), WithToken(token), WithCAData(caData...))
+}
+
+// TryNewKubernetesClientInsecure is the same as NewKubernetesClientInsecure, but returns an error instead of panic
+func TryNewKubernetesClientInsecure(url string, token string) (*KubernetesClient, error) {
+	return NewClient(WithUrl(url), WithToken(token), WithInsecureSkipVerify())
+}
+
+// TryNewKubernetesClientWithDefaultKubeConfig is the same as NewKubernetesClientWithDefaultKubeConfig,
+// but returns an error instead of panic
+func TryNewKubernetesClientWithDefaultKubeConfig() (*KubernetesClient, error) {
+	return NewClient(WithDefaultKubeConfig())
+}
+
+// TryNewKubernetesClientWithKubeConfig is the same as NewKubernetesClientWithKubeConfig,
+// but returns an error instead of panic
+func TryNewKubernetesClientWithKubeConfig(kubeConfig string) (*KubernetesClient, error) {
+	return NewClient(WithKubeConfig(kubeConfig))
+}
+
+// TryNewKubernetesClientWithKubeConfigContext is the same as NewKubernetesClientWithKubeConfigContext,
+// but returns an error instead of panic
+func TryNewKubernetesClientWithKubeConfigContext(kubeConfig string, contextName string) (*KubernetesClient, error) {
+	return NewClient(WithKubeConfigContext(kubeConfig, contextName))
+}
+
+// TryNewKubernetesClientWithAnalyzer is the same as NewKubernetesClientWithAnalyzer,
+// but returns an error instead of panic
+func TryNewKubernetesClientWithAnalyzer(url string, token string, analyzer *KubernetesAnalyzer, caData ...[]byte) (*KubernetesClient, error) {
+	opts := []Option{WithUrl(url), WithAnalyzer(analyzer), WithCAData(caData...)}
+	if len(token) != 0 {
+		opts = append(opts, WithToken(token))
+	}
+	return NewClient(opts...)
 }
 
-func NewKubernetesClientWithAnalyzer(url string, token string, analyzer *KubernetesAnalyzer) *KubernetesClient {
-	return createClient(url, token, &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: true},
-		}}, analyzer)
+// Init discovers all Kubernetes resources, an error is returned if any group
+// cannot be discovered, and the resources of the other groups are still usable
+func (client *KubernetesClient) Init() error {
+	return client.InitWithContext(context.Background())
 }
 
-func (client *KubernetesClient) Init() {
+func (client *KubernetesClient) InitWithContext(ctx context.Context) error {
 	// not initialized
 	if len(client.analyzer.RuleBase.KindToFullKindMapper) == 0 {
 		// initialing
-		client.analyzer.Learning(client)
+		return client.analyzer.LearningWithContext(ctx, client)
 	}
+	return nil
 }
 
 /************************************************************
@@ -133,12 +191,18 @@ func (client *KubernetesClient) Init() {
  *************************************************************/
 
 func (client *KubernetesClient) CreateResource(jsonStr string) ([]byte, error) {
+	return client.CreateResourceWithContext(context.Background(), jsonStr)
+}
+
+func (client *KubernetesClient) CreateResourceWithContext(ctx context.Context, jsonStr string) ([]byte, error) {
 
 	inputJson := gjson.Parse(jsonStr)
 
-	url := client.CreateResourceUrl(fullKind(inputJson), namespace(inputJson))
+	ns := client.objectNamespace(fullKind(inputJson), namespace(inputJson))
+	url := client.CreateResourceUrl(fullKind(inputJson), ns)
 
-	req, err := client.createRequest("POST", url, strings.NewReader(jsonStr))
+	ctx = withRequestInfo(ctx, RequestInfo{Verb: "create", FullKind: fullKind(inputJson), Namespace: ns, Name: name(inputJson)})
+	req, err := client.createRequest(ctx, "POST", url, strings.NewReader(jsonStr))
 	if err != nil {
 		return nil, err
 	}
@@ -152,11 +216,17 @@ func (client *KubernetesClient) CreateResource(jsonStr string) ([]byte, error) {
 }
 
 func (client *KubernetesClient) UpdateResource(jsonStr string) ([]byte, error) {
+	return client.UpdateResourceWithContext(context.Background(), jsonStr)
+}
+
+func (client *KubernetesClient) UpdateResourceWithContext(ctx context.Context, jsonStr string) ([]byte, error) {
 
 	inputJson := gjson.Parse(jsonStr)
 
-	url := client.UpdateResourceUrl(fullKind(inputJson), namespace(inputJson), name(inputJson))
-	req, err := client.createRequest("PUT", url, strings.NewReader(jsonStr))
+	ns := client.objectNamespace(fullKind(inputJson), namespace(inputJson))
+	url := client.UpdateResourceUrl(fullKind(inputJson), ns, name(inputJson))
+	ctx = withRequestInfo(ctx, RequestInfo{Verb: "update", FullKind: fullKind(inputJson), Namespace: ns, Name: name(inputJson)})
+	req, err := client.createRequest(ctx, "PUT", url, strings.NewReader(jsonStr))
 	if err != nil {
 		return nil, err
 	}
@@ -170,14 +240,20 @@ func (client *KubernetesClient) UpdateResource(jsonStr string) ([]byte, error) {
 }
 
 func (client *KubernetesClient) DeleteResource(kind string, namespace string, name string) ([]byte, error) {
+	return client.DeleteResourceWithContext(context.Background(), kind, namespace, name)
+}
+
+func (client *KubernetesClient) DeleteResourceWithContext(ctx context.Context, kind string, namespace string, name string) ([]byte, error) {
 
 	fullKind, err := toFullKind(kind, client.analyzer.RuleBase.KindToFullKindMapper)
 	if err != nil {
 		return nil, err
 	}
 
+	namespace = client.objectNamespace(fullKind, namespace)
 	url := client.DeleteResourceUrl(fullKind, namespace, name)
-	req, err := client.createRequest("DELETE", url, nil)
+	ctx = withRequestInfo(ctx, RequestInfo{Verb: "delete", FullKind: fullKind, Namespace: namespace, Name: name})
+	req, err := client.createRequest(ctx, "DELETE", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -191,14 +267,20 @@ func (client *KubernetesClient) DeleteResource(kind string, namespace string, na
 }
 
 func (client *KubernetesClient) GetResource(kind string, namespace string, name string) ([]byte, error) {
+	return client.GetResourceWithContext(context.Background(), kind, namespace, name)
+}
+
+func (client *KubernetesClient) GetResourceWithContext(ctx context.Context, kind string, namespace string, name string) ([]byte, error) {
 
 	fullKind, err := toFullKind(kind, client.analyzer.RuleBase.KindToFullKindMapper)
 	if err != nil {
 		return nil, err
 	}
 
+	namespace = client.objectNamespace(fullKind, namespace)
 	url := client.GetResourceUrl(fullKind, namespace, name)
-	req, err := client.createRequest("GET", url, nil)
+	ctx = withRequestInfo(ctx, RequestInfo{Verb: "get", FullKind: fullKind, Namespace: namespace, Name: name})
+	req, err := client.createRequest(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -212,6 +294,10 @@ func (client *KubernetesClient) GetResource(kind string, namespace string, name
 }
 
 func (client *KubernetesClient) ListResources(kind string, namespace string) ([]byte, error) {
+	return client.ListResourcesWithContext(context.Background(), kind, namespace)
+}
+
+func (client *KubernetesClient) ListResourcesWithContext(ctx context.Context, kind string, namespace string) ([]byte, error) {
 
 	fullKind, err := toFullKind(kind, client.analyzer.RuleBase.KindToFullKindMapper)
 	if err != nil {
@@ -219,7 +305,8 @@ func (client *KubernetesClient) ListResources(kind string, namespace string) ([]
 	}
 
 	url := client.ListResourcesUrl(fullKind, namespace)
-	req, err := client.createRequest("GET", url, nil)
+	ctx = withRequestInfo(ctx, RequestInfo{Verb: "list", FullKind: fullKind, Namespace: namespace})
+	req, err := client.createRequest(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -233,10 +320,16 @@ func (client *KubernetesClient) ListResources(kind string, namespace string) ([]
 }
 
 func (client *KubernetesClient) UpdateResourceStatus(jsonStr string) ([]byte, error) {
+	return client.UpdateResourceStatusWithContext(context.Background(), jsonStr)
+}
+
+func (client *KubernetesClient) UpdateResourceStatusWithContext(ctx context.Context, jsonStr string) ([]byte, error) {
 	inputJson := gjson.Parse(jsonStr)
 
-	url := client.UpdateResourceStatusUrl(fullKind(inputJson), namespace(inputJson), name(inputJson))
-	req, err := client.createRequest("PUT", url, strings.NewReader(jsonStr))
+	ns := client.objectNamespace(fullKind(inputJson), namespace(inputJson))
+	url := client.UpdateResourceStatusUrl(fullKind(inputJson), ns, name(inputJson))
+	ctx = withRequestInfo(ctx, RequestInfo{Verb: "update", FullKind: fullKind(inputJson), Namespace: ns, Name: name(inputJson), Subresource: "status"})
+	req, err := client.createRequest(ctx, "PUT", url, strings.NewReader(jsonStr))
 	if err != nil {
 		return nil, err
 	}
@@ -251,13 +344,19 @@ func (client *KubernetesClient) UpdateResourceStatus(jsonStr string) ([]byte, er
 
 // BindResources TODO
 func (client *KubernetesClient) BindResources(pod gjson.Result, host string) ([]byte, error) {
+	return client.BindResourcesWithContext(context.Background(), pod, host)
+}
+
+func (client *KubernetesClient) BindResourcesWithContext(ctx context.Context, pod gjson.Result, host string) ([]byte, error) {
 	var podJson = make(map[string]interface{})
 	podJson["apiVersion"] = "v1"
 	podJson["kind"] = "Binding"
 
 	var meta = make(map[string]interface{})
 	meta["name"] = pod.Get("metadata").Get("name").String()
-	meta["namespace"] = pod.Get("metadata").Get("namespace").String()
+	fullKind := fullKind(pod)
+	namespace := client.objectNamespace(fullKind, namespace(pod))
+	meta["namespace"] = namespace
 	podJson["metadata"] = meta
 
 	var target = make(map[string]interface{})
@@ -266,12 +365,15 @@ func (client *KubernetesClient) BindResources(pod gjson.Result, host string) ([]
 	target["name"] = host
 	podJson["target"] = target
 
-	fullKind := fullKind(pod)
-	namespace := namespace(pod)
 	url := client.BindingResourceStatusUrl(fullKind, namespace, name(pod))
+	ctx = withRequestInfo(ctx, RequestInfo{Verb: "create", FullKind: fullKind, Namespace: namespace, Name: name(pod), Subresource: "binding"})
 
 	jsonBytes, _ := json.Marshal(podJson)
-	req, _ := client.createRequest("POST", url, strings.NewReader(string(jsonBytes)))
+	req, err := client.createRequest(ctx, "POST", url, strings.NewReader(string(jsonBytes)))
+	if err != nil {
+		return nil, err
+	}
+
 	value, err := client.doRequest(req)
 	if err != nil {
 		return nil, err
@@ -281,37 +383,52 @@ func (client *KubernetesClient) BindResources(pod gjson.Result, host string) ([]
 }
 
 func (client *KubernetesClient) WatchResource(kind string, namespace string, name string, watcher *KubernetesWatcher) {
+	if err := client.WatchResourceWithContext(context.Background(), kind, namespace, name, watcher); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// WatchResourceWithContext blocks until the context is cancelled or the watch
+// ends, which is reported as an error
+func (client *KubernetesClient) WatchResourceWithContext(ctx context.Context, kind string, namespace string, name string, watcher *KubernetesWatcher) error {
 
 	ruleBase := client.analyzer.RuleBase
 	fullKind, err := toFullKind(kind, ruleBase.KindToFullKindMapper)
 
 	if err != nil {
-		fmt.Println(err)
-		return
+		return err
 	}
 
+	namespace = client.objectNamespace(fullKind, namespace)
 	url := ruleBase.FullKindToApiPrefixMapper[fullKind] + "/watch/"
 	url += namespacePath(ruleBase.FullKindToNamespaceMapper[fullKind], namespace)
 	url += ruleBase.FullKindToNameMapper[fullKind] + "/" + name
 	url += "/?watch=true&timeoutSeconds=315360000"
-	watcher.Watching(url)
+	ctx = withRequestInfo(ctx, RequestInfo{Verb: "watch", FullKind: fullKind, Namespace: namespace, Name: name})
+	return watcher.WatchingWithContext(ctx, url)
 }
 
 func (client *KubernetesClient) WatchResources(kind string, namespace string, watcher *KubernetesWatcher) {
+	if err := client.WatchResourcesWithContext(context.Background(), kind, namespace, watcher); err != nil {
+		fmt.Println(err)
+	}
+}
+
+func (client *KubernetesClient) WatchResourcesWithContext(ctx context.Context, kind string, namespace string, watcher *KubernetesWatcher) error {
 
 	ruleBase := client.analyzer.RuleBase
 	fullKind, err := toFullKind(kind, ruleBase.KindToFullKindMapper)
 
 	if err != nil {
-		fmt.Println(err)
-		return
+		return err
 	}
 
 	url := ruleBase.FullKindToApiPrefixMapper[fullKind] + "/watch/"
 	url += namespacePath(ruleBase.FullKindToNamespaceMapper[fullKind], namespace)
 	url += ruleBase.FullKindToNameMapper[fullKind]
 	url += "/?watch=true&timeoutSeconds=315360000"
-	watcher.Watching(url)
+	ctx = withRequestInfo(ctx, RequestInfo{Verb: "watch", FullKind: fullKind, Namespace: namespace})
+	return watcher.WatchingWithContext(ctx, url)
 }
 
 /************************************************************
@@ -321,18 +438,27 @@ func (client *KubernetesClient) WatchResources(kind string, namespace string, wa
  *************************************************************/
 
 func (client *KubernetesClient) ListResourcesWithLabelSelector(kind string, namespace string, labels map[string]string) ([]byte, error) {
+	return client.ListResourcesWithLabelSelectorWithContext(context.Background(), kind, namespace, labels)
+}
+
+func (client *KubernetesClient) ListResourcesWithLabelSelectorWithContext(ctx context.Context, kind string, namespace string, labels map[string]string) ([]byte, error) {
 	fullKind, err := toFullKind(kind, client.analyzer.RuleBase.KindToFullKindMapper)
 	if err != nil {
 		return nil, err
 	}
 
 	url := client.ListResourcesUrl(fullKind, namespace) + "?labelSelector="
+	ctx = withRequestInfo(ctx, RequestInfo{Verb: "list", FullKind: fullKind, Namespace: namespace})
 	for key, value := range labels {
 		url += key + "%3D" + value + ","
 	}
 	url = url[:len(url)-1]
 
-	req, _ := client.createRequest("GET", url, nil)
+	req, err := client.createRequest(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
 	value, err := client.doRequest(req)
 	if err != nil {
 		return nil, err
@@ -348,18 +474,27 @@ func (client *KubernetesClient) ListResourcesWithLabelSelector(kind string, name
  *************************************************************/
 
 func (client *KubernetesClient) ListResourcesWithFieldSelector(kind string, namespace string, fields map[string]string) ([]byte, error) {
+	return client.ListResourcesWithFieldSelectorWithContext(context.Background(), kind, namespace, fields)
+}
+
+func (client *KubernetesClient) ListResourcesWithFieldSelectorWithContext(ctx context.Context, kind string, namespace string, fields map[string]string) ([]byte, error) {
 	fullKind, err := toFullKind(kind, client.analyzer.RuleBase.KindToFullKindMapper)
 	if err != nil {
 		return nil, err
 	}
 
 	url := client.ListResourcesUrl(fullKind, namespace) + "?fieldSelector="
+	ctx = withRequestInfo(ctx, RequestInfo{Verb: "list", FullKind: fullKind, Namespace: namespace})
 	for key, value := range fields {
 		url += key + "%3D" + value + ","
 	}
 	url = url[:len(url)-1]
 
-	req, _ := client.createRequest("GET", url, nil)
+	req, err := client.createRequest(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
 	value, err := client.doRequest(req)
 	if err != nil {
 		return nil, err
@@ -374,44 +509,206 @@ func (client *KubernetesClient) ListResourcesWithFieldSelector(kind string, name
  *
  *************************************************************/
 
+// doRequest sends the request, and returns the body of the response
 func (client *KubernetesClient) doRequest(request *http.Request) ([]byte, error) {
-	res, err := client.http.Do(request)
+	var body []byte
+	err := client.doRequestWith(request, true, func(res *http.Response) (err error) {
+		body, err = ioutil.ReadAll(res.Body)
+		return err
+	})
 	if err != nil {
-		return nil, errors.New("request error:" + err.Error())
+		return nil, err
 	}
+	return body, nil
+}
 
-	if !(res.StatusCode >= http.StatusOK && res.StatusCode < http.StatusMultipleChoices) {
-		return nil, fmt.Errorf("wrong request status: %v\n", res)
+// doRequestWith sends the request and consumes the successful response with read, the request
+// is sent again according to the retry policy. A failed read is retried only if retryRead,
+// since the response may have been partly consumed
+func (client *KubernetesClient) doRequestWith(request *http.Request, retryRead bool, read func(res *http.Response) error) (err error) {
+	request, endSpan := client.traceRequest(request)
+
+	var res *http.Response
+	attempt := 1
+	defer func() {
+		endSpan(res, attempt-1, err)
+	}()
+
+	for ; ; attempt++ {
+		res, err = client.openRequest(request)
+		if err == nil {
+			err = read(res)
+			res.Body.Close()
+			if err == nil || !retryRead {
+				return err
+			}
+		}
+
+		delay, retry := client.retry.backoff(request.Method, attempt, res, err)
+		if !retry {
+			return err
+		}
+		if sleep(request.Context(), delay) != nil {
+			return err
+		}
+		client.metrics.requestRetried(RequestInfoFrom(request))
+		if request, err = rewindRequest(request); err != nil {
+			return err
+		}
+	}
+}
+
+// openRequest sends the request once. The body of a 2xx response must be closed by the caller,
+// otherwise a StatusError is returned with the closed response, which is nil if no response is received
+func (client *KubernetesClient) openRequest(request *http.Request) (*http.Response, error) {
+	if client.limiter != nil {
+		if err := client.limiter.wait(request.Context()); err != nil {
+			return nil, err
+		}
 	}
 
-	defer res.Body.Close()
+	cancel := context.CancelFunc(func() {})
+	if client.timeout > 0 {
+		var ctx context.Context
+		ctx, cancel = context.WithTimeout(request.Context(), client.timeout)
+		request = request.WithContext(ctx)
+	}
 
-	body, err := ioutil.ReadAll(res.Body)
+	res, err := client.send(request)
 	if err != nil {
-		return nil, err
+		cancel()
+		return nil, fmt.Errorf("request error: %w", err)
 	}
 
-	return body, nil
+	if res.StatusCode == http.StatusUnauthorized && client.reloadToken(request) {
+		// the token has been rotated, try again with the new one
+		res.Body.Close()
+		res, err = client.send(request)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("request error: %w", err)
+		}
+	}
+
+	if !(res.StatusCode >= http.StatusOK && res.StatusCode < http.StatusMultipleChoices) {
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		cancel()
+		return res, newStatusError(res.StatusCode, body)
+	}
+
+	// the timeout covers reading the body
+	res.Body = &cancelOnClose{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (body *cancelOnClose) Close() error {
+	defer body.cancel()
+	return body.ReadCloser.Close()
 }
 
-func (client *KubernetesClient) createRequest(method, url string, body io.Reader) (*http.Request, error) {
-	req, err := http.NewRequest(method, url, body)
+func (client *KubernetesClient) createRequest(ctx context.Context, method, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 
 	if err != nil {
 		return nil, err
 	}
 
-	if len(client.Token) != 0 {
-		req.Header.Add("Authorization", "Bearer "+client.Token)
+	token, err := client.token()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(token) != 0 {
+		req.Header.Add("Authorization", "Bearer "+token)
 	}
 
 	if body != nil {
 		req.Header.Add("Content-Type", "application/json")
 	}
 
+	if len(client.userAgent) != 0 {
+		req.Header.Set("User-Agent", client.userAgent)
+	}
+
+	if client.impersonate != nil {
+		client.impersonate.setHeaders(req.Header)
+	}
+
 	return req, nil
 }
 
+// clusterConfig returns a copy of how the client reaches the server, which is the base of the
+// issued Configs. The server of a client connected through a unix socket is unknown to others
+func (client *KubernetesClient) clusterConfig() (*Config, error) {
+	if client.cluster == nil {
+		return nil, errors.New("the client is connected through a unix socket, which cannot be put into a kubeconfig")
+	}
+	config := *client.cluster
+	return &config, nil
+}
+
+// objectNamespace is the namespace of an operation on one object. Like kubectl, an empty namespace
+// of a namespaced kind means the default namespace of the client, and then default. Lists and watches
+// of a kind are not defaulted, where an empty namespace means all namespaces
+func (client *KubernetesClient) objectNamespace(fullKind string, namespace string) string {
+	if len(namespace) != 0 || !client.analyzer.RuleBase.FullKindToNamespaceMapper[fullKind] {
+		return namespace
+	}
+	return client.namespaceOrDefault(namespace)
+}
+
+// namespaceOrDefault falls back to the default namespace of the client, and then to default
+func (client *KubernetesClient) namespaceOrDefault(namespace string) string {
+	if len(namespace) != 0 {
+		return namespace
+	}
+	if len(client.Namespace) != 0 {
+		return client.Namespace
+	}
+	return "default"
+}
+
+func (client *KubernetesClient) token() (string, error) {
+	if client.tokens != nil {
+		return client.tokens.Token()
+	}
+	return client.Token, nil
+}
+
+// reloadToken is called after the request is rejected with 401, and reports
+// whether the request can be sent again with a new token
+func (client *KubernetesClient) reloadToken(request *http.Request) bool {
+	source, ok := client.tokens.(invalidator)
+	if !ok {
+		return false
+	}
+	source.invalidate()
+
+	token, err := client.tokens.Token()
+	if err != nil || "Bearer "+token == request.Header.Get("Authorization") {
+		return false
+	}
+
+	if request.Body != nil {
+		if request.GetBody == nil {
+			return false
+		}
+		body, err := request.GetBody()
+		if err != nil {
+			return false
+		}
+		request.Body = body
+	}
+	request.Header.Set("Authorization", "Bearer "+token)
+	return true
+}
+
 func name(jsonObj gjson.Result) string {
 	return jsonObj.Get("metadata").Get("name").String()
 }
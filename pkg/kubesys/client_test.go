@@ -0,0 +1,82 @@
+/**
+ * Copyright (2021, ) Institute of Software, Chinese Academy of Sciences
+ */
+
+package kubesys
+
+import (
+	"errors"
+	"github.com/tidwall/gjson"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type failingTokenSource struct {
+	err error
+}
+
+func (source failingTokenSource) Token() (string, error) {
+	return "", source.err
+}
+
+func TestSelectorListsReturnTokenErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s", r.URL)
+	}))
+	defer server.Close()
+
+	tokenErr := errors.New("no token")
+	client := newPodClient(t, server)
+	client.tokens = failingTokenSource{err: tokenErr}
+
+	if _, err := client.ListResourcesWithLabelSelector("Pod", "default", map[string]string{"app": "nginx"}); !errors.Is(err, tokenErr) {
+		t.Fatalf("expected the token error, got %v", err)
+	}
+	if _, err := client.ListResourcesWithFieldSelector("Pod", "default", map[string]string{"status.phase": "Running"}); !errors.Is(err, tokenErr) {
+		t.Fatalf("expected the token error, got %v", err)
+	}
+	pod := []byte(`{"apiVersion":"v1","kind":"Pod","metadata":{"name":"busybox","namespace":"default"}}`)
+	if _, err := client.BindResources(gjson.ParseBytes(pod), "node1"); !errors.Is(err, tokenErr) {
+		t.Fatalf("expected the token error, got %v", err)
+	}
+}
+
+func TestObjectOperationsUseDefaultNamespace(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		w.Write([]byte(`{"kind":"Pod","metadata":{"name":"busybox"}}`))
+	}))
+	defer server.Close()
+	client := newPodClient(t, server)
+	client.Namespace = "team"
+
+	pod := `{"apiVersion":"v1","kind":"Pod","metadata":{"name":"busybox"}}`
+	calls := []func() error{
+		func() error { _, err := client.CreateResource(pod); return err },
+		func() error { _, err := client.GetResource("Pod", "", "busybox"); return err },
+		func() error { _, err := client.UpdateResource(pod); return err },
+		func() error { _, err := client.DeleteResource("Pod", "", "busybox"); return err },
+		func() error { _, err := client.GetResource("Pod", "other", "busybox"); return err },
+		func() error { _, err := client.ListResources("Pod", ""); return err },
+	}
+	for _, call := range calls {
+		if err := call(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := []string{
+		"POST /api/v1/namespaces/team/pods",
+		"GET /api/v1/namespaces/team/pods/busybox",
+		"PUT /api/v1/namespaces/team/pods/busybox",
+		"DELETE /api/v1/namespaces/team/pods/busybox",
+		"GET /api/v1/namespaces/other/pods/busybox",
+		"GET /api/v1/pods",
+	}
+	if strings.Join(paths, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("unexpected requests\n%s", strings.Join(paths, "\n"))
+	}
+}
@@ -0,0 +1,232 @@
+/**
+ * Copyright (2021, ) Institute of Software, Chinese Academy of Sciences
+ */
+
+package kubesys
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/tidwall/gjson"
+	"strings"
+	"time"
+)
+
+/**
+ * this class is used for issuing client certificates through the
+ * certificates.k8s.io CertificateSigningRequest API, see
+ * https://kubernetes.io/docs/reference/access-authn-authz/certificate-signing-requests/
+ *
+ *      date  : 2026/10/18
+ *      since : v2.0.4
+ */
+
+const (
+	csrUrl                 = "/apis/certificates.k8s.io/v1/certificatesigningrequests"
+	csrFullKind            = "certificates.k8s.io.CertificateSigningRequest"
+	csrApiClientSignerName = "kubernetes.io/kube-apiserver-client"
+	csrDefaultTimeout      = 5 * time.Minute
+	csrPollInterval        = time.Second
+)
+
+type CertificateRequest struct {
+	UserName          string        // required, the common name of the certificate
+	Groups            []string      // optional, the organizations of the certificate
+	Name              string        // optional, the name of the CertificateSigningRequest, generated if empty
+	SignerName        string        // optional, kubernetes.io/kube-apiserver-client by default
+	ExpirationSeconds int32         // optional, the requested duration of the certificate
+	Approve           bool          // optional, approves the request, which requires the approve permission on the signer
+	Timeout           time.Duration // optional, how long to wait for the certificate, 5 minutes by default
+}
+
+// IssueClientCertificate generates a private key, submits a CertificateSigningRequest for it and
+// waits until the certificate is issued. The returned Config authenticates with the certificate
+// against the server of the client, with the CA, TLS server name and proxy of the client, and can be
+// used by HTTPClientFor or NewKubeConfigFor. A client connected through a unix socket cannot issue Configs
+func (client *KubernetesClient) IssueClientCertificate(request CertificateRequest) (*Config, error) {
+	return client.IssueClientCertificateWithContext(context.Background(), request)
+}
+
+func (client *KubernetesClient) IssueClientCertificateWithContext(ctx context.Context, request CertificateRequest) (*Config, error) {
+	if len(request.UserName) == 0 {
+		return nil, errors.New("user name is required")
+	}
+	config, err := client.clusterConfig()
+	if err != nil {
+		return nil, err
+	}
+	signerName := request.SignerName
+	if len(signerName) == 0 {
+		signerName = csrApiClientSignerName
+	}
+	timeout := request.Timeout
+	if timeout <= 0 {
+		timeout = csrDefaultTimeout
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	csrDER, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: request.UserName, Organization: request.Groups},
+	}, key)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := client.createCSR(ctx, request, signerName,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrDER}))
+	if err != nil {
+		return nil, fmt.Errorf("creating CertificateSigningRequest: %w", err)
+	}
+
+	if request.Approve {
+		if err := client.approveCSR(ctx, name); err != nil {
+			return nil, fmt.Errorf("approving CertificateSigningRequest %s: %w", name, err)
+		}
+	}
+
+	certificate, err := client.waitForCertificate(ctx, name, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	config.ClientCertificateData = certificate
+	config.ClientKeyData = base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+	config.Namespace = client.Namespace
+	return config, nil
+}
+
+// createCSR returns the name of the created CertificateSigningRequest
+func (client *KubernetesClient) createCSR(ctx context.Context, request CertificateRequest, signerName string, csrPEM []byte) (string, error) {
+	metadata := map[string]interface{}{}
+	if len(request.Name) != 0 {
+		metadata["name"] = request.Name
+	} else {
+		metadata["generateName"] = "csr-"
+	}
+
+	spec := map[string]interface{}{
+		"request":    base64.StdEncoding.EncodeToString(csrPEM),
+		"signerName": signerName,
+		"usages":     []string{"digital signature", "client auth"},
+	}
+	if request.ExpirationSeconds > 0 {
+		spec["expirationSeconds"] = request.ExpirationSeconds
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"apiVersion": "certificates.k8s.io/v1",
+		"kind":       "CertificateSigningRequest",
+		"metadata":   metadata,
+		"spec":       spec,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	ctx = withRequestInfo(ctx, RequestInfo{Verb: "create", FullKind: csrFullKind, Name: request.Name})
+	req, err := client.createRequest(ctx, "POST", client.Url+csrUrl, strings.NewReader(string(body)))
+	if err != nil {
+		return "", err
+	}
+	value, err := client.doRequest(req)
+	if err != nil {
+		return "", err
+	}
+	return gjson.GetBytes(value, "metadata.name").String(), nil
+}
+
+// approveCSR adds the Approved condition through the approval subresource
+func (client *KubernetesClient) approveCSR(ctx context.Context, name string) error {
+	req, err := client.createRequest(withRequestInfo(ctx, RequestInfo{Verb: "get", FullKind: csrFullKind, Name: name}), "GET", client.Url+csrUrl+"/"+name, nil)
+	if err != nil {
+		return err
+	}
+	value, err := client.doRequest(req)
+	if err != nil {
+		return err
+	}
+
+	csr := make(map[string]interface{})
+	if err := json.Unmarshal(value, &csr); err != nil {
+		return err
+	}
+	status, _ := csr["status"].(map[string]interface{})
+	if status == nil {
+		status = make(map[string]interface{})
+		csr["status"] = status
+	}
+	conditions, _ := status["conditions"].([]interface{})
+	status["conditions"] = append(conditions, map[string]interface{}{
+		"type":    "Approved",
+		"status":  "True",
+		"reason":  "KubesysApprove",
+		"message": "approved by kubesys client",
+	})
+
+	body, err := json.Marshal(csr)
+	if err != nil {
+		return err
+	}
+	ctx = withRequestInfo(ctx, RequestInfo{Verb: "update", FullKind: csrFullKind, Name: name, Subresource: "approval"})
+	req, err = client.createRequest(ctx, "PUT", client.Url+csrUrl+"/"+name+"/approval", strings.NewReader(string(body)))
+	if err != nil {
+		return err
+	}
+	_, err = client.doRequest(req)
+	return err
+}
+
+// waitForCertificate polls the CertificateSigningRequest until status.certificate is set,
+// and returns it base64 encoded. A Denied or Failed request is reported as an error
+func (client *KubernetesClient) waitForCertificate(ctx context.Context, name string, timeout time.Duration) (string, error) {
+	deadline := time.Now().Add(timeout)
+	ctx = withRequestInfo(ctx, RequestInfo{Verb: "get", FullKind: csrFullKind, Name: name})
+	for {
+		req, err := client.createRequest(ctx, "GET", client.Url+csrUrl+"/"+name, nil)
+		if err != nil {
+			return "", err
+		}
+		value, err := client.doRequest(req)
+		if err != nil {
+			return "", fmt.Errorf("getting CertificateSigningRequest %s: %w", name, err)
+		}
+
+		status := gjson.GetBytes(value, "status")
+		for _, condition := range status.Get("conditions").Array() {
+			switch condition.Get("type").String() {
+			case "Denied", "Failed":
+				return "", fmt.Errorf("CertificateSigningRequest %s is %s: %s %s", name,
+					strings.ToLower(condition.Get("type").String()),
+					condition.Get("reason").String(), condition.Get("message").String())
+			}
+		}
+		if certificate := status.Get("certificate").String(); len(certificate) != 0 {
+			return certificate, nil
+		}
+
+		if time.Now().Add(csrPollInterval).After(deadline) {
+			return "", fmt.Errorf("timed out waiting for the certificate of CertificateSigningRequest %s", name)
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(csrPollInterval):
+		}
+	}
+}
@@ -0,0 +1,174 @@
+/**
+ * Copyright (2021, ) Institute of Software, Chinese Academy of Sciences
+ */
+
+package kubesys
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+/**
+ * this class is used for telling which step fails when creating or
+ * initializing a client, use errors.Is to check them, for example
+ *
+ *      if errors.Is(err, kubesys.ErrDiscovery) { ... }
+ *
+ *      date  : 2026/10/18
+ *      since : v2.0.4
+ */
+
+var (
+	ErrInvalidUrl        = errors.New("invalid url")
+	ErrInvalidToken      = errors.New("invalid token")
+	ErrInvalidKubeConfig = errors.New("invalid kubeconfig")
+	ErrInCluster         = errors.New("unable to load in-cluster configuration")
+	ErrDiscovery         = errors.New("discovery failed")
+
+	// the continue token of a paginated list is too old, the list has to be restarted
+	ErrContinueExpired = errors.New("continue token expired")
+)
+
+/************************************************************
+ *
+ *      status
+ *
+ *************************************************************/
+
+// the reasons of a metav1.Status, see
+// https://kubernetes.io/docs/reference/kubernetes-api/common-definitions/status/
+const (
+	StatusReasonUnknown         = ""
+	StatusReasonUnauthorized    = "Unauthorized"
+	StatusReasonForbidden       = "Forbidden"
+	StatusReasonNotFound        = "NotFound"
+	StatusReasonAlreadyExists   = "AlreadyExists"
+	StatusReasonConflict        = "Conflict"
+	StatusReasonGone            = "Gone"
+	StatusReasonInvalid         = "Invalid"
+	StatusReasonServerTimeout   = "ServerTimeout"
+	StatusReasonTimeout         = "Timeout"
+	StatusReasonTooManyRequests = "TooManyRequests"
+	StatusReasonBadRequest      = "BadRequest"
+	StatusReasonExpired         = "Expired"
+)
+
+// StatusError is returned when the server responds with a non-2xx code, the reason,
+// message and details are parsed from the metav1.Status body. Use errors.As or the
+// Is* predicates to check it, for example
+//
+//	if kubesys.IsNotFound(err) { ... }
+type StatusError struct {
+	Code    int            `json:"code"`
+	Reason  string         `json:"reason,omitempty"`
+	Message string         `json:"message,omitempty"`
+	Details *StatusDetails `json:"details,omitempty"`
+}
+
+type StatusDetails struct {
+	Name              string        `json:"name,omitempty"`
+	Group             string        `json:"group,omitempty"`
+	Kind              string        `json:"kind,omitempty"`
+	UID               string        `json:"uid,omitempty"`
+	Causes            []StatusCause `json:"causes,omitempty"`
+	RetryAfterSeconds int           `json:"retryAfterSeconds,omitempty"`
+}
+
+// StatusCause tells which field is wrong, mostly for Invalid
+type StatusCause struct {
+	Type    string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+	Field   string `json:"field,omitempty"`
+}
+
+func (err *StatusError) Error() string {
+	if len(err.Message) != 0 {
+		return err.Message
+	}
+	if len(err.Reason) != 0 {
+		return fmt.Sprintf("the server responded with %d %s", err.Code, err.Reason)
+	}
+	return fmt.Sprintf("the server responded with %d %s", err.Code, http.StatusText(err.Code))
+}
+
+// newStatusError parses the body of a non-2xx response, which may not be a
+// metav1.Status if it comes from a proxy, then the body is used as the message
+func newStatusError(code int, body []byte) *StatusError {
+	status := new(StatusError)
+	if err := json.Unmarshal(body, status); err == nil && (len(status.Reason) != 0 || len(status.Message) != 0) {
+		// the code of the response is more reliable than the one in the body
+		status.Code = code
+		return status
+	}
+
+	status = &StatusError{Code: code}
+	if text := strings.TrimSpace(string(body)); len(text) != 0 {
+		status.Message = fmt.Sprintf("the server responded with %d %s: %s", code, http.StatusText(code), text)
+	}
+	return status
+}
+
+// reasonAndCode returns StatusReasonUnknown and 0 if err is not a StatusError
+func reasonAndCode(err error) (string, int) {
+	var status *StatusError
+	if errors.As(err, &status) {
+		return status.Reason, status.Code
+	}
+	return StatusReasonUnknown, 0
+}
+
+// isReason checks the reason, or the code if the server does not tell the reason
+func isReason(err error, reason string, code int) bool {
+	r, c := reasonAndCode(err)
+	return r == reason || r == StatusReasonUnknown && c == code
+}
+
+func IsUnauthorized(err error) bool {
+	return isReason(err, StatusReasonUnauthorized, http.StatusUnauthorized)
+}
+
+func IsForbidden(err error) bool {
+	return isReason(err, StatusReasonForbidden, http.StatusForbidden)
+}
+
+func IsNotFound(err error) bool {
+	return isReason(err, StatusReasonNotFound, http.StatusNotFound)
+}
+
+// IsAlreadyExists is the 409 of creating, while IsConflict is the 409 of updating
+func IsAlreadyExists(err error) bool {
+	reason, _ := reasonAndCode(err)
+	return reason == StatusReasonAlreadyExists
+}
+
+func IsConflict(err error) bool {
+	return isReason(err, StatusReasonConflict, http.StatusConflict)
+}
+
+// IsGone means the requested resourceVersion is too old, such as an expired continue token
+func IsGone(err error) bool {
+	reason, code := reasonAndCode(err)
+	return reason == StatusReasonGone || reason == StatusReasonExpired || reason == StatusReasonUnknown && code == http.StatusGone
+}
+
+func IsInvalid(err error) bool {
+	return isReason(err, StatusReasonInvalid, http.StatusUnprocessableEntity)
+}
+
+func IsBadRequest(err error) bool {
+	return isReason(err, StatusReasonBadRequest, http.StatusBadRequest)
+}
+
+func IsTooManyRequests(err error) bool {
+	reason, code := reasonAndCode(err)
+	return reason == StatusReasonTooManyRequests || code == http.StatusTooManyRequests
+}
+
+func IsTimeout(err error) bool {
+	reason, code := reasonAndCode(err)
+	return reason == StatusReasonTimeout || reason == StatusReasonServerTimeout || code == http.StatusGatewayTimeout
+}
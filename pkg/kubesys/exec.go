@@ -0,0 +1,249 @@
+/**
+ * Copyright (2021, ) Institute of Software, Chinese Academy of Sciences
+ */
+
+package kubesys
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+/**
+ * this class is used for getting credentials from an exec plugin, which
+ * prints a client.authentication.k8s.io ExecCredential to stdout, see
+ * https://kubernetes.io/docs/reference/access-authn-authz/authentication/#client-go-credential-plugins
+ *
+ *      date  : 2026/10/18
+ *      since : v2.0.4
+ */
+
+const (
+	execInfoEnv = "KUBERNETES_EXEC_INFO"
+
+	// the credential is refreshed a little before it expires, so
+	// that a request is never sent with an expired one
+	execRefreshMargin = 30 * time.Second
+)
+
+type execCredential struct {
+	ApiVersion string                `json:"apiVersion"`
+	Kind       string                `json:"kind"`
+	Spec       execCredentialSpec    `json:"spec"`
+	Status     *execCredentialStatus `json:"status,omitempty"`
+}
+
+type execCredentialSpec struct {
+	Cluster     *execCluster `json:"cluster,omitempty"`
+	Interactive bool         `json:"interactive"`
+}
+
+type execCluster struct {
+	Server                   string `json:"server"`
+	CertificateAuthorityData []byte `json:"certificate-authority-data,omitempty"`
+}
+
+type execCredentialStatus struct {
+	ExpirationTimestamp   *time.Time `json:"expirationTimestamp,omitempty"`
+	Token                 string     `json:"token,omitempty"`
+	ClientCertificateData string     `json:"clientCertificateData,omitempty"`
+	ClientKeyData         string     `json:"clientKeyData,omitempty"`
+}
+
+type execAuthenticator struct {
+	config  *ExecConfig
+	cluster *execCluster
+
+	// closes the connections authenticated with an old client certificate
+	onRotate func()
+
+	mutex      sync.Mutex
+	token      string
+	cert       *tls.Certificate
+	expiration time.Time // zero means never expires
+	valid      bool
+}
+
+func newExecAuthenticator(config *Config) (*execAuthenticator, error) {
+	execConfig := config.Exec
+	if len(execConfig.Command) == 0 {
+		return nil, errors.New("exec plugin: command is empty")
+	}
+	switch execConfig.ApiVersion {
+	case "client.authentication.k8s.io/v1", "client.authentication.k8s.io/v1beta1":
+	default:
+		return nil, fmt.Errorf("exec plugin: unsupported apiVersion %q", execConfig.ApiVersion)
+	}
+
+	authenticator := &execAuthenticator{config: execConfig}
+	if execConfig.ProvideClusterInfo {
+		caData, err := config.caData()
+		if err != nil {
+			return nil, err
+		}
+		authenticator.cluster = &execCluster{
+			Server:                   config.Server,
+			CertificateAuthorityData: caData,
+		}
+	}
+	return authenticator, nil
+}
+
+// credential returns the cached token and client certificate, and runs
+// the plugin again if they are missing or about to expire
+func (authenticator *execAuthenticator) credential() (string, *tls.Certificate, error) {
+	authenticator.mutex.Lock()
+	defer authenticator.mutex.Unlock()
+
+	if authenticator.valid && (authenticator.expiration.IsZero() ||
+		time.Now().Add(execRefreshMargin).Before(authenticator.expiration)) {
+		return authenticator.token, authenticator.cert, nil
+	}
+
+	status, err := authenticator.run()
+	if err != nil {
+		return "", nil, err
+	}
+
+	var cert *tls.Certificate
+	if len(status.ClientCertificateData) != 0 || len(status.ClientKeyData) != 0 {
+		pair, err := tls.X509KeyPair([]byte(status.ClientCertificateData), []byte(status.ClientKeyData))
+		if err != nil {
+			return "", nil, fmt.Errorf("exec plugin: invalid client certificate: %v", err)
+		}
+		cert = &pair
+	}
+
+	rotated := authenticator.cert != nil && (cert == nil ||
+		!bytes.Equal(authenticator.cert.Certificate[0], cert.Certificate[0]))
+
+	authenticator.token = status.Token
+	authenticator.cert = cert
+	authenticator.expiration = time.Time{}
+	if status.ExpirationTimestamp != nil {
+		authenticator.expiration = *status.ExpirationTimestamp
+	}
+	authenticator.valid = true
+
+	if rotated && authenticator.onRotate != nil {
+		authenticator.onRotate()
+	}
+	return authenticator.token, authenticator.cert, nil
+}
+
+// invalidate forces the plugin to run again on the next request,
+// it is called when the server rejects the cached credential
+func (authenticator *execAuthenticator) invalidate() {
+	authenticator.mutex.Lock()
+	defer authenticator.mutex.Unlock()
+	authenticator.valid = false
+}
+
+func (authenticator *execAuthenticator) run() (*execCredentialStatus, error) {
+	config := authenticator.config
+
+	info, err := json.Marshal(&execCredential{
+		ApiVersion: config.ApiVersion,
+		Kind:       "ExecCredential",
+		Spec: execCredentialSpec{
+			Cluster:     authenticator.cluster,
+			Interactive: false,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	env := os.Environ()
+	for _, e := range config.Env {
+		env = append(env, e.Name+"="+e.Value)
+	}
+	env = append(env, execInfoEnv+"="+string(info))
+
+	stdout := new(bytes.Buffer)
+	cmd := exec.Command(config.Command, config.Args...)
+	cmd.Env = env
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		if (errors.Is(err, exec.ErrNotFound) || errors.Is(err, os.ErrNotExist)) && len(config.InstallHint) != 0 {
+			return nil, fmt.Errorf("exec plugin: %v\n%s", err, config.InstallHint)
+		}
+		return nil, fmt.Errorf("exec plugin: %v", err)
+	}
+
+	credential := new(execCredential)
+	if err := json.Unmarshal(stdout.Bytes(), credential); err != nil {
+		return nil, fmt.Errorf("exec plugin: decoding stdout: %v", err)
+	}
+	if credential.ApiVersion != config.ApiVersion {
+		return nil, fmt.Errorf("exec plugin: apiVersion %s does not match the expected %s",
+			credential.ApiVersion, config.ApiVersion)
+	}
+	if credential.Status == nil {
+		return nil, errors.New("exec plugin: status is missing")
+	}
+	if len(credential.Status.Token) == 0 && len(credential.Status.ClientCertificateData) == 0 {
+		return nil, errors.New("exec plugin: neither token nor clientCertificateData is returned")
+	}
+	return credential.Status, nil
+}
+
+// getClientCertificate is used as tls.Config.GetClientCertificate
+func (authenticator *execAuthenticator) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	_, cert, err := authenticator.credential()
+	if err != nil {
+		return nil, err
+	}
+	if cert == nil {
+		// no certificate is sent
+		return &tls.Certificate{}, nil
+	}
+	return cert, nil
+}
+
+/************************************************************
+ *
+ *      RoundTripper
+ *
+ *************************************************************/
+
+type execRoundTripper struct {
+	authenticator *execAuthenticator
+	base          http.RoundTripper
+}
+
+func (rt *execRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if len(req.Header.Get("Authorization")) != 0 {
+		// the caller sets its own credential
+		return rt.base.RoundTrip(req)
+	}
+
+	token, _, err := rt.authenticator.credential()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(token) != 0 {
+		req = req.Clone(req.Context())
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	res, err := rt.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode == http.StatusUnauthorized {
+		rt.authenticator.invalidate()
+	}
+	return res, nil
+}
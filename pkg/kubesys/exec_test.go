@@ -0,0 +1,277 @@
+/**
+ * Copyright (2021, ) Institute of Software, Chinese Academy of Sciences
+ */
+
+package kubesys
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeExecPlugin writes a script printing an ExecCredential with the status, every run
+// appends a line to the returned file
+func writeExecPlugin(t *testing.T, status string) (*ExecConfig, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the plugin is a shell script")
+	}
+	dir := t.TempDir()
+	runs := filepath.Join(dir, "runs")
+	script := filepath.Join(dir, "plugin.sh")
+	content := fmt.Sprintf(`#!/bin/sh
+echo run >> %q
+cat <<'EOF'
+{"apiVersion":"client.authentication.k8s.io/v1","kind":"ExecCredential","status":%s}
+EOF
+`, runs, status)
+	if err := os.WriteFile(script, []byte(content), 0700); err != nil {
+		t.Fatal(err)
+	}
+	return &ExecConfig{Command: script, ApiVersion: "client.authentication.k8s.io/v1"}, runs
+}
+
+func execRuns(t *testing.T, runs string) int {
+	t.Helper()
+	data, err := os.ReadFile(runs)
+	if os.IsNotExist(err) {
+		return 0
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Count(string(data), "run\n")
+}
+
+// bearerServer accepts the bearer token
+func bearerServer(t *testing.T, token string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{}`)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestExecWithTransportWithoutTLSConfig(t *testing.T) {
+	execConfig, _ := writeExecPlugin(t, `{"token":"exec-token"}`)
+	server := bearerServer(t, "exec-token")
+
+	for name, transport := range map[string]*http.Transport{"new": {}, "default": http.DefaultTransport.(*http.Transport)} {
+		t.Run(name, func(t *testing.T) {
+			client, err := NewClient(WithConfig(&Config{Server: server.URL, Exec: execConfig}), WithTransport(transport))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if code := get(t, client, server.URL); code != http.StatusOK {
+				t.Fatalf("expected the exec token to be accepted, got %d", code)
+			}
+			// Clone configures HTTP/2 of the transport, which may set TLSClientConfig
+			if transport.TLSClientConfig != nil && transport.TLSClientConfig.GetClientCertificate != nil {
+				t.Fatal("the given transport is changed")
+			}
+		})
+	}
+}
+
+func TestExecDoesNotChangeSharedTransport(t *testing.T) {
+	transport := &http.Transport{TLSClientConfig: &tls.Config{MinVersion: tls.VersionTLS12}}
+	for _, token := range []string{"first", "second"} {
+		execConfig, _ := writeExecPlugin(t, fmt.Sprintf(`{"token":%q}`, token))
+		server := bearerServer(t, token)
+		client, err := NewClient(WithConfig(&Config{Server: server.URL, Exec: execConfig}), WithTransport(transport))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code := get(t, client, server.URL); code != http.StatusOK {
+			t.Fatalf("expected the exec token %s to be accepted, got %d", token, code)
+		}
+	}
+	if transport.TLSClientConfig.GetClientCertificate != nil {
+		t.Fatal("the shared transport is changed")
+	}
+}
+
+func TestExecCachesCredential(t *testing.T) {
+	tests := []struct {
+		name       string
+		expiration time.Duration
+		runs       int
+	}{
+		{name: "never expires", runs: 1},
+		{name: "valid", expiration: time.Hour, runs: 1},
+		// within the refresh margin, the plugin runs for every request
+		{name: "about to expire", expiration: execRefreshMargin / 2, runs: 3},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status := `{"token":"exec-token"}`
+			if test.expiration != 0 {
+				status = fmt.Sprintf(`{"token":"exec-token","expirationTimestamp":%q}`,
+					time.Now().Add(test.expiration).UTC().Format(time.RFC3339))
+			}
+			execConfig, runs := writeExecPlugin(t, status)
+			server := bearerServer(t, "exec-token")
+			client, err := NewClient(WithConfig(&Config{Server: server.URL, Exec: execConfig}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 3; i++ {
+				if code := get(t, client, server.URL); code != http.StatusOK {
+					t.Fatalf("expected the exec token to be accepted, got %d", code)
+				}
+			}
+			if got := execRuns(t, runs); got != test.runs {
+				t.Fatalf("expected %d runs, got %d", test.runs, got)
+			}
+		})
+	}
+}
+
+func TestExecRunsAgainAfterUnauthorized(t *testing.T) {
+	execConfig, runs := writeExecPlugin(t, `{"token":"revoked"}`)
+	server := bearerServer(t, "exec-token")
+	client, err := NewClient(WithConfig(&Config{Server: server.URL, Exec: execConfig}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if code := get(t, client, server.URL); code != http.StatusUnauthorized {
+			t.Fatalf("expected the revoked token to be rejected, got %d", code)
+		}
+	}
+	if got := execRuns(t, runs); got != 2 {
+		t.Fatalf("expected the plugin to run again after 401, got %d runs", got)
+	}
+}
+
+// testCertificate returns a self-signed client certificate of the user and its key in PEM
+func testCertificate(t *testing.T, userName string) ([]byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: userName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestExecKeepsStaticClientCertificate(t *testing.T) {
+	var subjects []string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, cert := range r.TLS.PeerCertificates {
+			subjects = append(subjects, cert.Subject.CommonName)
+		}
+		fmt.Fprint(w, `{}`)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	certPEM, keyPEM := testCertificate(t, "alice")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	execConfig, _ := writeExecPlugin(t, `{"token":"exec-token"}`)
+	client, err := NewClient(WithConfig(&Config{
+		Server:                   server.URL,
+		CertificateAuthorityData: base64.StdEncoding.EncodeToString(caPEM),
+		ClientCertificateData:    base64.StdEncoding.EncodeToString(certPEM),
+		ClientKeyData:            base64.StdEncoding.EncodeToString(keyPEM),
+		Exec:                     execConfig,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := get(t, client, server.URL); code != http.StatusOK {
+		t.Fatalf("unexpected status %d", code)
+	}
+	if len(subjects) != 1 || subjects[0] != "alice" {
+		t.Fatalf("expected the static client certificate, got %v", subjects)
+	}
+}
+
+func TestExecClientCertificateRotation(t *testing.T) {
+	var subjects []string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		subjects = append(subjects, r.TLS.PeerCertificates[0].Subject.CommonName)
+		if len(subjects) == 1 {
+			// rejects the first certificate, so that the plugin runs again
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{}`)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	// the plugin returns the first certificate, and then the second one
+	dir := t.TempDir()
+	var statuses []string
+	for _, userName := range []string{"first", "second"} {
+		certPEM, keyPEM := testCertificate(t, userName)
+		status, _ := json.Marshal(map[string]string{"clientCertificateData": string(certPEM), "clientKeyData": string(keyPEM)})
+		statuses = append(statuses, string(status))
+	}
+	execConfig, runs := writeExecPlugin(t, statuses[0])
+	next, _ := writeExecPlugin(t, statuses[1])
+	script := filepath.Join(dir, "plugin.sh")
+	content := fmt.Sprintf("#!/bin/sh\nif [ -f %q ]; then exec %q; fi\nexec %q\n", runs, next.Command, execConfig.Command)
+	if err := os.WriteFile(script, []byte(content), 0700); err != nil {
+		t.Fatal(err)
+	}
+	execConfig = &ExecConfig{Command: script, ApiVersion: execConfig.ApiVersion}
+
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	client, err := NewClient(WithConfig(&Config{
+		Server:                   server.URL,
+		CertificateAuthorityData: base64.StdEncoding.EncodeToString(caPEM),
+		Exec:                     execConfig,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := get(t, client, server.URL); code != http.StatusUnauthorized {
+		t.Fatalf("expected the first certificate to be rejected, got %d", code)
+	}
+	if code := get(t, client, server.URL); code != http.StatusOK {
+		t.Fatalf("expected the rotated certificate to be accepted, got %d", code)
+	}
+	if strings.Join(subjects, ",") != "first,second" {
+		t.Fatalf("expected the certificate to be rotated, got %v", subjects)
+	}
+}
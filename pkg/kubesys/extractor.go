@@ -5,6 +5,7 @@
 package kubesys
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -19,17 +20,16 @@ import (
  *      date  : 2022/4/3
  *      since : v2.0.0
  */
-func extract(client *KubernetesClient, registry *Registry) {
+func extract(ctx context.Context, client *KubernetesClient, registry *Registry) error {
 	// request kube-apiserver, such as http://IP:6443.
-	registryRequest, err := client.createRequest("GET", client.Url, nil)
+	registryRequest, err := client.createRequest(ctx, "GET", client.Url, nil)
 	if err != nil {
-		errors.New(err.Error())
+		return fmt.Errorf("%w for paths: %w", ErrDiscovery, err)
 	}
 
 	registryStringValues, err := client.doRequest(registryRequest)
 	if err != nil {
-		fmt.Println("request registry string values error, ", err)
-		panic(err)
+		return fmt.Errorf("%w for paths: %w", ErrDiscovery, err)
 	}
 	// if it is successful, the output is.
 	// {
@@ -92,15 +92,27 @@ func extract(client *KubernetesClient, registry *Registry) {
 	//        "/version"
 	//    ]
 	registryValues := make(map[string]interface{})
-	json.Unmarshal([]byte(registryStringValues), &registryValues)
+	if err := json.Unmarshal(registryStringValues, &registryValues); err != nil {
+		return fmt.Errorf("%w for paths: %w", ErrDiscovery, err)
+	}
+
+	paths, ok := registryValues["paths"].([]interface{})
+	if !ok {
+		return fmt.Errorf("%w for paths: paths is missing", ErrDiscovery)
+	}
 
-	for _, v := range registryValues["paths"].([]interface{}) {
-		path := v.(string)
+	// an unavailable group, such as an aggregated api, does not stop the others
+	var errs []error
+	for _, v := range paths {
+		path, _ := v.(string)
 		// just check /api and /apis
 		if strings.HasPrefix(path, "/api") &&
 			// go to /apis/node.k8s.io/v1 rather than /apis/node.k8s.io, or goto /api/v1
 			(len(strings.Split(path, "/")) == 4 || strings.EqualFold(path, "/api/v1")) {
-			register(client, client.Url+path, registry)
+			if err := register(ctx, client, client.Url+path, registry); err != nil {
+				errs = append(errs, err)
+			}
 		}
 	}
+	return errors.Join(errs...)
 }
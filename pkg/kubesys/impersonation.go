@@ -0,0 +1,78 @@
+/**
+ * Copyright (2021, ) Institute of Software, Chinese Academy of Sciences
+ */
+
+package kubesys
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+/**
+ * this class is used for acting as another user, see
+ * https://kubernetes.io/docs/reference/access-authn-authz/authentication/#user-impersonation
+ *
+ *      date  : 2026/10/18
+ *      since : v2.0.4
+ */
+
+type ImpersonationConfig struct {
+	UserName string
+	UID      string
+	Groups   []string
+	Extra    map[string][]string
+}
+
+// Impersonate returns a copy of the client acting as the given user, which
+// shares the transport and RuleBase of the client, so Init is not needed
+func (client *KubernetesClient) Impersonate(config ImpersonationConfig) (*KubernetesClient, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	impersonated := *client
+	impersonated.impersonate = config.deepCopy()
+	return &impersonated, nil
+}
+
+func (config *ImpersonationConfig) validate() error {
+	if len(config.UserName) == 0 && (len(config.UID) != 0 || len(config.Groups) != 0 || len(config.Extra) != 0) {
+		return errors.New("impersonating uid, groups or extra requires a user name")
+	}
+	return nil
+}
+
+func (config *ImpersonationConfig) deepCopy() *ImpersonationConfig {
+	copied := &ImpersonationConfig{
+		UserName: config.UserName,
+		UID:      config.UID,
+		Groups:   append([]string(nil), config.Groups...),
+	}
+	if config.Extra != nil {
+		copied.Extra = make(map[string][]string, len(config.Extra))
+		for key, values := range config.Extra {
+			copied.Extra[key] = append([]string(nil), values...)
+		}
+	}
+	return copied
+}
+
+func (config *ImpersonationConfig) setHeaders(header http.Header) {
+	if len(config.UserName) == 0 {
+		return
+	}
+	header.Set("Impersonate-User", config.UserName)
+	if len(config.UID) != 0 {
+		header.Set("Impersonate-Uid", config.UID)
+	}
+	for _, group := range config.Groups {
+		header.Add("Impersonate-Group", group)
+	}
+	for key, values := range config.Extra {
+		// the key is percent-encoded since header names are case-insensitive
+		for _, value := range values {
+			header.Add("Impersonate-Extra-"+url.PathEscape(key), value)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+/**
+ * Copyright (2021, ) Institute of Software, Chinese Academy of Sciences
+ */
+
+package kubesys
+
+import (
+	"log"
+	"net/http"
+	"time"
+)
+
+/**
+ * this class is used for adding cross-cutting behaviors to every request sent
+ * by a client, including retries, discovery and watches, such as
+ *
+ *      client.Use(kubesys.HeaderInterceptor(header), kubesys.LoggingInterceptor(nil))
+ *
+ * The first interceptor is the outermost one, it sees the request first and
+ * the response last
+ *
+ *      date  : 2026/10/18
+ *      since : v2.0.4
+ */
+
+// Invoker sends the request to the next interceptor, or to the server for the last one
+type Invoker func(request *http.Request) (*http.Response, error)
+
+// Interceptor may change the request, call next zero or more times, and change the
+// response or error. The request should be cloned before it is changed
+type Interceptor func(request *http.Request, next Invoker) (*http.Response, error)
+
+// Use appends interceptors, it should be called before the client is shared
+func (client *KubernetesClient) Use(interceptors ...Interceptor) {
+	// not appending in place, the copies made by Impersonate share the slice
+	client.interceptors = append(append([]Interceptor(nil), client.interceptors...), interceptors...)
+}
+
+// send is the only way to send requests to the server
+func (client *KubernetesClient) send(request *http.Request) (*http.Response, error) {
+	done := client.metrics.requestStarted(RequestInfoFrom(request))
+	res, err := client.invoker(0)(request)
+	if err != nil {
+		done(0)
+	} else {
+		done(res.StatusCode)
+	}
+	return res, err
+}
+
+func (client *KubernetesClient) invoker(index int) Invoker {
+	if index == len(client.interceptors) {
+		return client.http.Do
+	}
+	return func(request *http.Request) (*http.Response, error) {
+		return client.interceptors[index](request, client.invoker(index+1))
+	}
+}
+
+/************************************************************
+ *
+ *      built-in
+ *
+ *************************************************************/
+
+// LoggingInterceptor logs the method, url, status and duration of every request, the
+// headers are not logged since they carry credentials. A nil logger means log.Default
+func LoggingInterceptor(logger *log.Logger) Interceptor {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return func(request *http.Request, next Invoker) (*http.Response, error) {
+		start := time.Now()
+		res, err := next(request)
+		if err != nil {
+			logger.Printf("%s %s failed in %v: %v", request.Method, request.URL, time.Since(start), err)
+			return res, err
+		}
+		logger.Printf("%s %s %s in %v", request.Method, request.URL, res.Status, time.Since(start))
+		return res, err
+	}
+}
+
+// HeaderInterceptor sets the headers of every request, which replace the existing ones
+func HeaderInterceptor(header http.Header) Interceptor {
+	header = header.Clone()
+	return func(request *http.Request, next Invoker) (*http.Response, error) {
+		request = request.Clone(request.Context())
+		for key, values := range header {
+			request.Header[http.CanonicalHeaderKey(key)] = append([]string(nil), values...)
+		}
+		return next(request)
+	}
+}
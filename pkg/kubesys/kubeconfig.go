@@ -0,0 +1,433 @@
+/**
+ * Copyright (2021, ) Institute of Software, Chinese Academy of Sciences
+ */
+
+package kubesys
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+/**
+ * this class is used for parsing kubeconfig files, see
+ * https://kubernetes.io/docs/concepts/configuration/organize-cluster-access-kubeconfig/
+ *
+ *      date  : 2026/10/18
+ *      since : v2.0.4
+ */
+
+/************************************************************
+ *
+ *      struct
+ *
+ *************************************************************/
+
+type KubeConfig struct {
+	ApiVersion     string         `yaml:"apiVersion,omitempty"`
+	Kind           string         `yaml:"kind,omitempty"`
+	Clusters       []NamedCluster `yaml:"clusters"`
+	Users          []NamedUser    `yaml:"users"`
+	Contexts       []NamedContext `yaml:"contexts"`
+	CurrentContext string         `yaml:"current-context"`
+}
+
+type NamedCluster struct {
+	Name    string  `yaml:"name"`
+	Cluster Cluster `yaml:"cluster"`
+}
+
+type Cluster struct {
+	Server                   string `yaml:"server"`
+	ProxyURL                 string `yaml:"proxy-url,omitempty"`
+	TLSServerName            string `yaml:"tls-server-name,omitempty"`
+	InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify,omitempty"`
+	CertificateAuthority     string `yaml:"certificate-authority,omitempty"`
+	CertificateAuthorityData string `yaml:"certificate-authority-data,omitempty"`
+}
+
+type NamedUser struct {
+	Name string `yaml:"name"`
+	User User   `yaml:"user"`
+
+	// the kubeconfig file defining the user, the refreshed
+	// tokens of auth-provider are written back to it
+	location string
+}
+
+type User struct {
+	ClientCertificate     string      `yaml:"client-certificate,omitempty"`
+	ClientCertificateData string      `yaml:"client-certificate-data,omitempty"`
+	ClientKey             string      `yaml:"client-key,omitempty"`
+	ClientKeyData         string      `yaml:"client-key-data,omitempty"`
+	Token                 string      `yaml:"token,omitempty"`
+	TokenFile             string      `yaml:"tokenFile,omitempty"`
+	Username              string      `yaml:"username,omitempty"`
+	Password              string      `yaml:"password,omitempty"`
+	Exec                  *ExecConfig `yaml:"exec,omitempty"`
+
+	AuthProvider *AuthProviderConfig `yaml:"auth-provider,omitempty"`
+
+	Impersonate          string              `yaml:"as,omitempty"`
+	ImpersonateUID       string              `yaml:"as-uid,omitempty"`
+	ImpersonateGroups    []string            `yaml:"as-groups,omitempty"`
+	ImpersonateUserExtra map[string][]string `yaml:"as-user-extra,omitempty"`
+}
+
+// ExecConfig runs a credential plugin, see
+// https://kubernetes.io/docs/reference/access-authn-authz/authentication/#client-go-credential-plugins
+type ExecConfig struct {
+	Command            string       `yaml:"command"`
+	Args               []string     `yaml:"args,omitempty"`
+	Env                []ExecEnvVar `yaml:"env,omitempty"`
+	ApiVersion         string       `yaml:"apiVersion"`
+	InstallHint        string       `yaml:"installHint,omitempty"`
+	ProvideClusterInfo bool         `yaml:"provideClusterInfo,omitempty"`
+	InteractiveMode    string       `yaml:"interactiveMode,omitempty"`
+}
+
+// AuthProviderConfig is a legacy way to get credentials, only oidc is supported
+type AuthProviderConfig struct {
+	Name   string            `yaml:"name"`
+	Config map[string]string `yaml:"config,omitempty"`
+}
+
+type ExecEnvVar struct {
+	Name  string `yaml:"name"`
+	Value string `yaml:"value"`
+}
+
+type NamedContext struct {
+	Name    string  `yaml:"name"`
+	Context Context `yaml:"context"`
+}
+
+type Context struct {
+	Cluster   string `yaml:"cluster"`
+	User      string `yaml:"user"`
+	Namespace string `yaml:"namespace,omitempty"`
+}
+
+/************************************************************
+ *
+ *      parsing
+ *
+ *************************************************************/
+
+// LoadKubeConfig reads and parses the kubeconfig file at the given path
+func LoadKubeConfig(path string) (*KubeConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	kubeConfig, err := ParseKubeConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("kubeconfig %s: %v", path, err)
+	}
+
+	// like kubectl, file paths are relative to the kubeconfig file
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	kubeConfig.resolvePaths(dir)
+
+	location := filepath.Join(dir, filepath.Base(path))
+	for i := range kubeConfig.Users {
+		kubeConfig.Users[i].location = location
+	}
+	return kubeConfig, nil
+}
+
+// LoadKubeConfigs reads the given kubeconfig files and merges them in order
+// like kubectl, missing files are skipped, and os.ErrNotExist is returned if
+// none of them exists
+func LoadKubeConfigs(paths ...string) (*KubeConfig, error) {
+	kubeConfigs := make([]*KubeConfig, 0, len(paths))
+	for _, path := range paths {
+		kubeConfig, err := LoadKubeConfig(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		kubeConfigs = append(kubeConfigs, kubeConfig)
+	}
+	if len(kubeConfigs) == 0 {
+		return nil, fmt.Errorf("no kubeconfig found in [%s]: %w", strings.Join(paths, ", "), os.ErrNotExist)
+	}
+	return MergeKubeConfigs(kubeConfigs...), nil
+}
+
+// DefaultKubeConfigPaths returns the kubeconfig files used by kubectl, that is
+// the files listed in the KUBECONFIG environment variable, or $HOME/.kube/config
+func DefaultKubeConfigPaths() []string {
+	if env := os.Getenv("KUBECONFIG"); len(env) != 0 {
+		paths := make([]string, 0)
+		for _, path := range filepath.SplitList(env) {
+			if len(path) != 0 && !contains(paths, path) {
+				paths = append(paths, path)
+			}
+		}
+		return paths
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return []string{}
+	}
+	return []string{filepath.Join(home, ".kube", "config")}
+}
+
+// ParseKubeConfig parses the content of a kubeconfig file
+func ParseKubeConfig(data []byte) (*KubeConfig, error) {
+	kubeConfig := new(KubeConfig)
+	if err := yaml.Unmarshal(data, kubeConfig); err != nil {
+		return nil, err
+	}
+	return kubeConfig, nil
+}
+
+// MergeKubeConfigs merges kubeConfigs with kubectl's rules, the first
+// cluster, user or context with a given name wins, and so does the first
+// non-empty current-context
+func MergeKubeConfigs(kubeConfigs ...*KubeConfig) *KubeConfig {
+	merged := new(KubeConfig)
+	for _, kubeConfig := range kubeConfigs {
+		if len(merged.ApiVersion) == 0 {
+			merged.ApiVersion, merged.Kind = kubeConfig.ApiVersion, kubeConfig.Kind
+		}
+		if len(merged.CurrentContext) == 0 {
+			merged.CurrentContext = kubeConfig.CurrentContext
+		}
+		for _, cluster := range kubeConfig.Clusters {
+			if merged.GetCluster(cluster.Name) == nil {
+				merged.Clusters = append(merged.Clusters, cluster)
+			}
+		}
+		for _, user := range kubeConfig.Users {
+			if merged.GetUser(user.Name) == nil {
+				merged.Users = append(merged.Users, user)
+			}
+		}
+		for _, context := range kubeConfig.Contexts {
+			if merged.GetContext(context.Name) == nil {
+				merged.Contexts = append(merged.Contexts, context)
+			}
+		}
+	}
+	return merged
+}
+
+func (kubeConfig *KubeConfig) resolvePaths(dir string) {
+	for i := range kubeConfig.Clusters {
+		cluster := &kubeConfig.Clusters[i].Cluster
+		cluster.CertificateAuthority = resolvePath(dir, cluster.CertificateAuthority)
+	}
+	for i := range kubeConfig.Users {
+		user := &kubeConfig.Users[i].User
+		user.ClientCertificate = resolvePath(dir, user.ClientCertificate)
+		user.ClientKey = resolvePath(dir, user.ClientKey)
+		user.TokenFile = resolvePath(dir, user.TokenFile)
+		if user.Exec != nil && strings.ContainsRune(user.Exec.Command, filepath.Separator) {
+			// a bare command name is looked up in PATH instead
+			user.Exec.Command = resolvePath(dir, user.Exec.Command)
+		}
+	}
+}
+
+func resolvePath(dir string, path string) string {
+	if len(path) == 0 || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+/************************************************************
+ *
+ *      resolving
+ *
+ *************************************************************/
+
+// ToConfig resolves the given context into a Config, an empty
+// contextName means the current-context of the kubeconfig
+func (kubeConfig *KubeConfig) ToConfig(contextName string) (*Config, error) {
+	if len(contextName) == 0 {
+		contextName = kubeConfig.CurrentContext
+	}
+	if len(contextName) == 0 {
+		return nil, errors.New("current-context is not set in kubeconfig")
+	}
+
+	context := kubeConfig.GetContext(contextName)
+	if context == nil {
+		return nil, fmt.Errorf("context %s is not found in kubeconfig, available contexts: [%s]",
+			contextName, strings.Join(kubeConfig.GetContextNames(), ", "))
+	}
+	cluster := kubeConfig.GetCluster(context.Cluster)
+	if cluster == nil {
+		return nil, fmt.Errorf("cluster %s of context %s is not found in kubeconfig", context.Cluster, contextName)
+	}
+	namedUser := kubeConfig.getNamedUser(context.User)
+	if namedUser == nil {
+		return nil, fmt.Errorf("user %s of context %s is not found in kubeconfig", context.User, contextName)
+	}
+	if len(cluster.Server) == 0 {
+		return nil, fmt.Errorf("cluster %s has no server", context.Cluster)
+	}
+	user := &namedUser.User
+
+	return &Config{
+		Server:                   cluster.Server,
+		ProxyURL:                 cluster.ProxyURL,
+		TLSServerName:            cluster.TLSServerName,
+		Insecure:                 cluster.InsecureSkipTLSVerify,
+		ClientCertificate:        user.ClientCertificate,
+		ClientCertificateData:    user.ClientCertificateData,
+		ClientKey:                user.ClientKey,
+		ClientKeyData:            user.ClientKeyData,
+		CertificateAuthority:     cluster.CertificateAuthority,
+		CertificateAuthorityData: cluster.CertificateAuthorityData,
+		Token:                    user.Token,
+		TokenFile:                user.TokenFile,
+		Username:                 user.Username,
+		Password:                 user.Password,
+		Namespace:                context.Namespace,
+		Exec:                     user.Exec,
+		AuthProvider:             user.AuthProvider,
+		kubeConfigPath:           namedUser.location,
+		userName:                 namedUser.Name,
+		Impersonate: ImpersonationConfig{
+			UserName: user.Impersonate,
+			UID:      user.ImpersonateUID,
+			Groups:   user.ImpersonateGroups,
+			Extra:    user.ImpersonateUserExtra,
+		},
+	}, nil
+}
+
+func (kubeConfig *KubeConfig) GetContext(name string) *Context {
+	for i := range kubeConfig.Contexts {
+		if kubeConfig.Contexts[i].Name == name {
+			return &kubeConfig.Contexts[i].Context
+		}
+	}
+	return nil
+}
+
+func (kubeConfig *KubeConfig) GetCluster(name string) *Cluster {
+	for i := range kubeConfig.Clusters {
+		if kubeConfig.Clusters[i].Name == name {
+			return &kubeConfig.Clusters[i].Cluster
+		}
+	}
+	return nil
+}
+
+func (kubeConfig *KubeConfig) GetUser(name string) *User {
+	if namedUser := kubeConfig.getNamedUser(name); namedUser != nil {
+		return &namedUser.User
+	}
+	return nil
+}
+
+func (kubeConfig *KubeConfig) getNamedUser(name string) *NamedUser {
+	for i := range kubeConfig.Users {
+		if kubeConfig.Users[i].Name == name {
+			return &kubeConfig.Users[i]
+		}
+	}
+	return nil
+}
+
+// GetContextNames returns the names of all contexts in order
+func (kubeConfig *KubeConfig) GetContextNames() []string {
+	names := make([]string, len(kubeConfig.Contexts))
+	for i, context := range kubeConfig.Contexts {
+		names[i] = context.Name
+	}
+	return names
+}
+
+/************************************************************
+ *
+ *      rendering
+ *
+ *************************************************************/
+
+// NewKubeConfigFor is the reverse of ToConfig, the cluster, user and
+// context are all named name, and the context is the current-context
+func NewKubeConfigFor(config *Config, name string) *KubeConfig {
+	return &KubeConfig{
+		ApiVersion: "v1",
+		Kind:       "Config",
+		Clusters: []NamedCluster{{
+			Name: name,
+			Cluster: Cluster{
+				Server:                   config.Server,
+				ProxyURL:                 config.ProxyURL,
+				TLSServerName:            config.TLSServerName,
+				InsecureSkipTLSVerify:    config.Insecure,
+				CertificateAuthority:     config.CertificateAuthority,
+				CertificateAuthorityData: config.CertificateAuthorityData,
+			},
+		}},
+		Users: []NamedUser{{
+			Name: name,
+			User: User{
+				ClientCertificate:     config.ClientCertificate,
+				ClientCertificateData: config.ClientCertificateData,
+				ClientKey:             config.ClientKey,
+				ClientKeyData:         config.ClientKeyData,
+				Token:                 config.Token,
+				TokenFile:             config.TokenFile,
+				Username:              config.Username,
+				Password:              config.Password,
+				Exec:                  config.Exec,
+				AuthProvider:          config.AuthProvider,
+				Impersonate:           config.Impersonate.UserName,
+				ImpersonateUID:        config.Impersonate.UID,
+				ImpersonateGroups:     config.Impersonate.Groups,
+				ImpersonateUserExtra:  config.Impersonate.Extra,
+			},
+		}},
+		Contexts: []NamedContext{{
+			Name: name,
+			Context: Context{
+				Cluster:   name,
+				User:      name,
+				Namespace: config.Namespace,
+			},
+		}},
+		CurrentContext: name,
+	}
+}
+
+// Marshal encodes the kubeconfig as yaml, which can be written to a file
+// and loaded by LoadKubeConfig or kubectl
+func (kubeConfig *KubeConfig) Marshal() ([]byte, error) {
+	buf := new(strings.Builder)
+	encoder := yaml.NewEncoder(buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(kubeConfig); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return []byte(buf.String()), nil
+}
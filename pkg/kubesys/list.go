@@ -0,0 +1,230 @@
+/**
+ * Copyright (2021, ) Institute of Software, Chinese Academy of Sciences
+ */
+
+package kubesys
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+/**
+ * this class is used for listing large collections, page by page with limit and
+ * continue, or decoding the items one by one from the response, so that the
+ * memory does not grow with the size of the collection
+ *
+ *      date  : 2026/10/18
+ *      since : v2.0.4
+ */
+
+type ListOptions struct {
+	LabelSelector string // such as app=nginx,tier!=cache
+	FieldSelector string // such as status.phase=Running
+	Limit         int64  // the maximum number of items in a page, 0 means all
+	Continue      string // the continue token of the previous page
+}
+
+// ListPage is a page of a list, Continue is empty for the last page
+type ListPage struct {
+	Items [][]byte
+	ListMeta
+}
+
+// the page size of ListAllResources if the limit is not given, same as kubectl
+const defaultPageLimit = 500
+
+// ListMeta is the metadata of a list
+type ListMeta struct {
+	ResourceVersion    string `json:"resourceVersion,omitempty"`
+	Continue           string `json:"continue,omitempty"`
+	RemainingItemCount *int64 `json:"remainingItemCount,omitempty"`
+}
+
+func (options ListOptions) query() string {
+	query := url.Values{}
+	if len(options.LabelSelector) != 0 {
+		query.Set("labelSelector", options.LabelSelector)
+	}
+	if len(options.FieldSelector) != 0 {
+		query.Set("fieldSelector", options.FieldSelector)
+	}
+	if options.Limit > 0 {
+		query.Set("limit", strconv.FormatInt(options.Limit, 10))
+	}
+	if len(options.Continue) != 0 {
+		query.Set("continue", options.Continue)
+	}
+	if len(query) == 0 {
+		return ""
+	}
+	return "?" + query.Encode()
+}
+
+func (client *KubernetesClient) StreamResources(kind string, namespace string, options ListOptions, handler func(item []byte) error) (*ListMeta, error) {
+	return client.StreamResourcesWithContext(context.Background(), kind, namespace, options, handler)
+}
+
+// StreamResourcesWithContext calls handler with every item of the list in order, each item is a
+// JSON object. An error of handler stops the list and is returned. The list is not sent again once
+// the response is received, since some items may have been handled
+func (client *KubernetesClient) StreamResourcesWithContext(ctx context.Context, kind string, namespace string, options ListOptions, handler func(item []byte) error) (*ListMeta, error) {
+	req, err := client.createListRequest(ctx, kind, namespace, options)
+	if err != nil {
+		return nil, err
+	}
+
+	var meta *ListMeta
+	err = client.doRequestWith(req, false, func(res *http.Response) (err error) {
+		meta, err = decodeList(res.Body, handler)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+func (client *KubernetesClient) ListResourcesPage(kind string, namespace string, options ListOptions) (*ListPage, error) {
+	return client.ListResourcesPageWithContext(context.Background(), kind, namespace, options)
+}
+
+// ListResourcesPageWithContext returns at most options.Limit items, the next page is
+// listed with the Continue of the returned page. An expired continue token is reported
+// as ErrContinueExpired, then the list has to be restarted without it
+func (client *KubernetesClient) ListResourcesPageWithContext(ctx context.Context, kind string, namespace string, options ListOptions) (*ListPage, error) {
+	req, err := client.createListRequest(ctx, kind, namespace, options)
+	if err != nil {
+		return nil, err
+	}
+
+	page := new(ListPage)
+	var meta *ListMeta
+	err = client.doRequestWith(req, true, func(res *http.Response) (err error) {
+		// the items of a failed attempt are dropped, since the page is read again
+		page.Items = nil
+		meta, err = decodeList(res.Body, func(item []byte) error {
+			page.Items = append(page.Items, item)
+			return nil
+		})
+		return err
+	})
+	if err != nil {
+		if len(options.Continue) != 0 && IsGone(err) {
+			return nil, fmt.Errorf("%w: %w", ErrContinueExpired, err)
+		}
+		return nil, err
+	}
+	page.ListMeta = *meta
+	return page, nil
+}
+
+func (client *KubernetesClient) ListAllResources(kind string, namespace string, options ListOptions, handler func(page *ListPage) error) error {
+	return client.ListAllResourcesWithContext(context.Background(), kind, namespace, options, handler)
+}
+
+// ListAllResourcesWithContext calls handler with every page in order, starting from options.Continue,
+// 500 items a page if options.Limit is not given. An error of handler stops the list and is returned.
+// If the continue token expires in the middle, ErrContinueExpired is returned, and the pages that
+// have been handled are no longer a consistent snapshot, the list should be restarted from the beginning
+func (client *KubernetesClient) ListAllResourcesWithContext(ctx context.Context, kind string, namespace string, options ListOptions, handler func(page *ListPage) error) error {
+	if options.Limit <= 0 {
+		options.Limit = defaultPageLimit
+	}
+	for {
+		page, err := client.ListResourcesPageWithContext(ctx, kind, namespace, options)
+		if err != nil {
+			return err
+		}
+		if err := handler(page); err != nil {
+			return err
+		}
+		if len(page.Continue) == 0 {
+			return nil
+		}
+		options.Continue = page.Continue
+	}
+}
+
+func (client *KubernetesClient) createListRequest(ctx context.Context, kind string, namespace string, options ListOptions) (*http.Request, error) {
+	fullKind, err := toFullKind(kind, client.analyzer.RuleBase.KindToFullKindMapper)
+	if err != nil {
+		return nil, err
+	}
+
+	url := client.ListResourcesUrl(fullKind, namespace) + options.query()
+	ctx = withRequestInfo(ctx, RequestInfo{Verb: "list", FullKind: fullKind, Namespace: namespace})
+	return client.createRequest(ctx, "GET", url, nil)
+}
+
+// decodeList walks the tokens of the list object, only one item is held in memory at a time
+func decodeList(reader io.Reader, handler func(item []byte) error) (*ListMeta, error) {
+	decoder := json.NewDecoder(reader)
+	if err := expectDelim(decoder, '{'); err != nil {
+		return nil, err
+	}
+
+	meta := new(ListMeta)
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch token {
+		case "metadata":
+			if err := decoder.Decode(meta); err != nil {
+				return nil, err
+			}
+		case "items":
+			token, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			if token == nil {
+				// null for an empty list
+				continue
+			}
+			if token != json.Delim('[') {
+				return nil, fmt.Errorf("decoding list: items is %v rather than an array", token)
+			}
+			for decoder.More() {
+				var item json.RawMessage
+				if err := decoder.Decode(&item); err != nil {
+					return nil, err
+				}
+				if err := handler(item); err != nil {
+					return nil, err
+				}
+			}
+			if err := expectDelim(decoder, ']'); err != nil {
+				return nil, err
+			}
+		default:
+			var skipped json.RawMessage
+			if err := decoder.Decode(&skipped); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := expectDelim(decoder, '}'); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+func expectDelim(decoder *json.Decoder, delim json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("decoding list: %v is expected, but got %v", delim, token)
+	}
+	return nil
+}
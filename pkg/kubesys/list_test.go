@@ -0,0 +1,98 @@
+/**
+ * Copyright (2021, ) Institute of Software, Chinese Academy of Sciences
+ */
+
+package kubesys
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newPodClient returns a client of the server, which knows Pod without discovery
+func newPodClient(t *testing.T, server *httptest.Server) *KubernetesClient {
+	t.Helper()
+	client, err := NewClient(WithUrl(server.URL), WithToken("token"),
+		WithRetryPolicy(&RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ruleBase := client.analyzer.RuleBase
+	ruleBase.KindToFullKindMapper = map[string][]string{"Pod": {"Pod"}}
+	ruleBase.FullKindToApiPrefixMapper = map[string]string{"Pod": server.URL + "/api/v1"}
+	ruleBase.FullKindToNameMapper = map[string]string{"Pod": "pods"}
+	ruleBase.FullKindToNamespaceMapper = map[string]bool{"Pod": true}
+	return client
+}
+
+// truncatingServer cuts off its first response after the first item
+func truncatingServer(t *testing.T) *httptest.Server {
+	var requests int32
+	const list = `{"kind":"PodList","metadata":{"resourceVersion":"7"},"items":[{"name":"a"},{"name":"b"}]}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.Header().Set("Content-Length", fmt.Sprint(len(list)))
+			fmt.Fprint(w, list[:len(list)-10])
+			return
+		}
+		fmt.Fprint(w, list)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestListResourcesPageRetriesTruncatedResponse(t *testing.T) {
+	client := newPodClient(t, truncatingServer(t))
+
+	page, err := client.ListResourcesPage("Pod", "default", ListOptions{Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 2 || string(page.Items[0]) != `{"name":"a"}` || string(page.Items[1]) != `{"name":"b"}` {
+		t.Fatalf("unexpected items %q", page.Items)
+	}
+	if page.ResourceVersion != "7" {
+		t.Fatalf("unexpected resourceVersion %q", page.ResourceVersion)
+	}
+}
+
+func TestListAllResourcesRetriesTruncatedResponse(t *testing.T) {
+	client := newPodClient(t, truncatingServer(t))
+
+	var items int
+	err := client.ListAllResources("Pod", "default", ListOptions{}, func(page *ListPage) error {
+		items += len(page.Items)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if items != 2 {
+		t.Fatalf("expected 2 items, got %d", items)
+	}
+}
+
+func TestListAllResourcesContinueExpired(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.URL.Query().Get("continue")) == 0 {
+			fmt.Fprint(w, `{"metadata":{"resourceVersion":"7","continue":"next"},"items":[{"name":"a"}]}`)
+			return
+		}
+		w.WriteHeader(http.StatusGone)
+		fmt.Fprint(w, `{"kind":"Status","status":"Failure","reason":"Expired","code":410,"message":"too old"}`)
+	}))
+	defer server.Close()
+	client := newPodClient(t, server)
+
+	err := client.ListAllResources("Pod", "default", ListOptions{Limit: 1}, func(page *ListPage) error {
+		return nil
+	})
+	if !errors.Is(err, ErrContinueExpired) || !IsGone(err) {
+		t.Fatalf("expected an expired continue token, got %v", err)
+	}
+}
@@ -5,6 +5,7 @@
 package kubesys
 
 import (
+	"context"
 	"fmt"
 )
 
@@ -13,9 +14,9 @@ import (
  *      date  : 2021/4/8
  */
 // TODO
-func listen(client *KubernetesClient, registry *Registry) {
+func listen(ctx context.Context, client *KubernetesClient, registry *Registry) error {
 
-	crds, _ := client.ListResources("CustomResourceDefinition", "")
+	crds, _ := client.ListResourcesWithContext(ctx, "CustomResourceDefinition", "")
 
 	items := ToJsonObject(crds).Get("items").Array()
 
@@ -27,7 +28,10 @@ func listen(client *KubernetesClient, registry *Registry) {
 		for j := 0; j < len(vers); j++ {
 			ver := vers[i].Get("name").String()
 			url := client.Url + "/apis/" + group + "/" + ver
-			register(client, url, registry)
+			if err := register(ctx, client, url, registry); err != nil {
+				return err
+			}
 		}
 	}
+	return nil
 }
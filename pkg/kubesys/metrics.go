@@ -0,0 +1,272 @@
+/**
+ * Copyright (2021, ) Institute of Software, Chinese Academy of Sciences
+ */
+
+package kubesys
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/**
+ * this class is used for recording how a client uses kube-apiserver, per verb and
+ * fullKind, and serving the metrics in the Prometheus text exposition format, see
+ * https://prometheus.io/docs/instrumenting/exposition_formats/
+ *
+ *      http.Handle("/metrics", client.Metrics())
+ *
+ *      date  : 2026/10/18
+ *      since : v2.0.4
+ */
+
+// the upper bounds in seconds of the request duration histogram
+var metricsDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+type Metrics struct {
+	mutex     sync.Mutex
+	requests  map[[3]string]uint64     // verb, fullKind and code
+	durations map[[2]string]*histogram // verb and fullKind
+	retries   map[[2]string]uint64     // verb and fullKind
+	inFlight  map[[2]string]int64      // verb and fullKind
+	watches   map[string]int64         // fullKind
+	events    map[[2]string]uint64     // fullKind and type
+}
+
+type histogram struct {
+	buckets []uint64 // not cumulative
+	sum     float64
+	count   uint64
+}
+
+// NewMetrics is used for sharing the metrics among clients with WithMetrics,
+// otherwise every client has its own
+func NewMetrics() *Metrics {
+	return &Metrics{
+		requests:  make(map[[3]string]uint64),
+		durations: make(map[[2]string]*histogram),
+		retries:   make(map[[2]string]uint64),
+		inFlight:  make(map[[2]string]int64),
+		watches:   make(map[string]int64),
+		events:    make(map[[2]string]uint64),
+	}
+}
+
+// Metrics returns the metrics of the client, which is an http.Handler
+func (client *KubernetesClient) Metrics() *Metrics {
+	return client.metrics
+}
+
+/************************************************************
+ *
+ *      recording
+ *
+ *************************************************************/
+
+// requestStarted counts the request in flight, and the returned function
+// records the result, code is empty if no response is received
+func (metrics *Metrics) requestStarted(info RequestInfo) func(code int) {
+	key := [2]string{info.Verb, info.FullKind}
+	start := time.Now()
+
+	metrics.mutex.Lock()
+	metrics.inFlight[key]++
+	metrics.mutex.Unlock()
+
+	return func(code int) {
+		duration := time.Since(start).Seconds()
+		codeLabel := "error"
+		if code != 0 {
+			codeLabel = strconv.Itoa(code)
+		}
+
+		metrics.mutex.Lock()
+		defer metrics.mutex.Unlock()
+		metrics.inFlight[key]--
+		metrics.requests[[3]string{info.Verb, info.FullKind, codeLabel}]++
+
+		h := metrics.durations[key]
+		if h == nil {
+			h = &histogram{buckets: make([]uint64, len(metricsDurationBuckets))}
+			metrics.durations[key] = h
+		}
+		for i, bound := range metricsDurationBuckets {
+			if duration <= bound {
+				h.buckets[i]++
+				break
+			}
+		}
+		h.sum += duration
+		h.count++
+	}
+}
+
+func (metrics *Metrics) requestRetried(info RequestInfo) {
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+	metrics.retries[[2]string{info.Verb, info.FullKind}]++
+}
+
+// watchOpened counts the open watch, and the returned function closes it
+func (metrics *Metrics) watchOpened(fullKind string) func() {
+	metrics.mutex.Lock()
+	metrics.watches[fullKind]++
+	metrics.mutex.Unlock()
+
+	return func() {
+		metrics.mutex.Lock()
+		defer metrics.mutex.Unlock()
+		metrics.watches[fullKind]--
+	}
+}
+
+func (metrics *Metrics) watchEvent(fullKind string, eventType string) {
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+	metrics.events[[2]string{fullKind, eventType}]++
+}
+
+/************************************************************
+ *
+ *      exposing
+ *
+ *************************************************************/
+
+func (metrics *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	metrics.WriteTo(w)
+}
+
+// WriteTo writes all the metrics in the Prometheus text exposition format
+func (metrics *Metrics) WriteTo(w io.Writer) (int64, error) {
+	// a slow scrape does not block the requests
+	metrics = metrics.snapshot()
+
+	counter := &countingWriter{writer: bufio.NewWriter(w)}
+
+	writeHeader(counter, "kubesys_client_requests_total", "counter",
+		"Number of requests sent to kube-apiserver, by verb, fullKind and status code.")
+	for _, key := range sortedKeys(metrics.requests) {
+		fmt.Fprintf(counter, "kubesys_client_requests_total{verb=%s,full_kind=%s,code=%s} %d\n",
+			quote(key[0]), quote(key[1]), quote(key[2]), metrics.requests[key])
+	}
+
+	writeHeader(counter, "kubesys_client_request_duration_seconds", "histogram",
+		"Latency of requests sent to kube-apiserver, by verb and fullKind.")
+	for _, key := range sortedKeys(metrics.durations) {
+		h := metrics.durations[key]
+		labels := "verb=" + quote(key[0]) + ",full_kind=" + quote(key[1])
+		cumulative := uint64(0)
+		for i, bound := range metricsDurationBuckets {
+			cumulative += h.buckets[i]
+			fmt.Fprintf(counter, "kubesys_client_request_duration_seconds_bucket{%s,le=%s} %d\n",
+				labels, quote(strconv.FormatFloat(bound, 'g', -1, 64)), cumulative)
+		}
+		fmt.Fprintf(counter, "kubesys_client_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, h.count)
+		fmt.Fprintf(counter, "kubesys_client_request_duration_seconds_sum{%s} %s\n", labels, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(counter, "kubesys_client_request_duration_seconds_count{%s} %d\n", labels, h.count)
+	}
+
+	writeHeader(counter, "kubesys_client_request_retries_total", "counter",
+		"Number of retried requests, by verb and fullKind.")
+	for _, key := range sortedKeys(metrics.retries) {
+		fmt.Fprintf(counter, "kubesys_client_request_retries_total{verb=%s,full_kind=%s} %d\n",
+			quote(key[0]), quote(key[1]), metrics.retries[key])
+	}
+
+	writeHeader(counter, "kubesys_client_requests_in_flight", "gauge",
+		"Number of requests waiting for the response headers, by verb and fullKind.")
+	for _, key := range sortedKeys(metrics.inFlight) {
+		fmt.Fprintf(counter, "kubesys_client_requests_in_flight{verb=%s,full_kind=%s} %d\n",
+			quote(key[0]), quote(key[1]), metrics.inFlight[key])
+	}
+
+	writeHeader(counter, "kubesys_client_open_watches", "gauge",
+		"Number of open watches, by fullKind.")
+	for _, key := range sortedKeys(metrics.watches) {
+		fmt.Fprintf(counter, "kubesys_client_open_watches{full_kind=%s} %d\n", quote(key), metrics.watches[key])
+	}
+
+	writeHeader(counter, "kubesys_client_watch_events_total", "counter",
+		"Number of received watch events, by fullKind and type.")
+	for _, key := range sortedKeys(metrics.events) {
+		fmt.Fprintf(counter, "kubesys_client_watch_events_total{full_kind=%s,type=%s} %d\n",
+			quote(key[0]), quote(key[1]), metrics.events[key])
+	}
+
+	if counter.err != nil {
+		return counter.n, counter.err
+	}
+	return counter.n, counter.writer.Flush()
+}
+
+// snapshot copies the metrics while holding the lock
+func (metrics *Metrics) snapshot() *Metrics {
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+
+	copied := NewMetrics()
+	for key, value := range metrics.requests {
+		copied.requests[key] = value
+	}
+	for key, h := range metrics.durations {
+		copied.durations[key] = &histogram{buckets: append([]uint64(nil), h.buckets...), sum: h.sum, count: h.count}
+	}
+	for key, value := range metrics.retries {
+		copied.retries[key] = value
+	}
+	for key, value := range metrics.inFlight {
+		copied.inFlight[key] = value
+	}
+	for key, value := range metrics.watches {
+		copied.watches[key] = value
+	}
+	for key, value := range metrics.events {
+		copied.events[key] = value
+	}
+	return copied
+}
+
+func writeHeader(w io.Writer, name string, metricType string, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+// quote escapes the label value with backslash, double-quote and line feed
+func quote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
+
+// sortedKeys makes the output stable
+func sortedKeys[K [2]string | [3]string | string, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+	})
+	return keys
+}
+
+type countingWriter struct {
+	writer *bufio.Writer
+	n      int64
+	err    error
+}
+
+func (counter *countingWriter) Write(p []byte) (int, error) {
+	if counter.err != nil {
+		return 0, counter.err
+	}
+	n, err := counter.writer.Write(p)
+	counter.n += int64(n)
+	counter.err = err
+	return n, err
+}
@@ -0,0 +1,83 @@
+/**
+ * Copyright (2021, ) Institute of Software, Chinese Academy of Sciences
+ */
+
+package kubesys
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// blockingWriter blocks every write until release is closed
+type blockingWriter struct {
+	writing chan struct{}
+	release chan struct{}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	select {
+	case w.writing <- struct{}{}:
+	default:
+	}
+	<-w.release
+	return len(p), nil
+}
+
+func TestMetricsWriteDoesNotBlockRequests(t *testing.T) {
+	metrics := NewMetrics()
+	// more than the buffer of the writer, so that the exposition is written in the middle
+	for i := 0; i < 200; i++ {
+		metrics.requestStarted(RequestInfo{Verb: "get", FullKind: fmt.Sprintf("Kind%d", i)})(200)
+	}
+
+	writer := &blockingWriter{writing: make(chan struct{}, 1), release: make(chan struct{})}
+	written := make(chan struct{})
+	go func() {
+		metrics.WriteTo(writer)
+		close(written)
+	}()
+	<-writer.writing
+
+	done := make(chan struct{})
+	go func() {
+		metrics.requestStarted(RequestInfo{Verb: "get", FullKind: "Pod"})(200)
+		metrics.watchEvent("Pod", "ADDED")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Error("the requests are blocked by a slow scrape")
+	}
+	close(writer.release)
+	<-written
+}
+
+func TestMetricsWriteTo(t *testing.T) {
+	metrics := NewMetrics()
+	metrics.requestStarted(RequestInfo{Verb: "list", FullKind: "Pod"})(200)
+	metrics.requestRetried(RequestInfo{Verb: "list", FullKind: "Pod"})
+	closeWatch := metrics.watchOpened("Pod")
+	metrics.watchEvent("Pod", "ADDED")
+	closeWatch()
+
+	out := new(strings.Builder)
+	if _, err := metrics.WriteTo(out); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		`kubesys_client_requests_total{verb="list",full_kind="Pod",code="200"} 1`,
+		`kubesys_client_request_duration_seconds_count{verb="list",full_kind="Pod"} 1`,
+		`kubesys_client_request_retries_total{verb="list",full_kind="Pod"} 1`,
+		`kubesys_client_requests_in_flight{verb="list",full_kind="Pod"} 0`,
+		`kubesys_client_open_watches{full_kind="Pod"} 0`,
+		`kubesys_client_watch_events_total{full_kind="Pod",type="ADDED"} 1`,
+	} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Errorf("missing %s in\n%s", line, out)
+		}
+	}
+}
@@ -0,0 +1,290 @@
+/**
+ * Copyright (2021, ) Institute of Software, Chinese Academy of Sciences
+ */
+
+package kubesys
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+/**
+ * this class is used for the oidc auth-provider of kubeconfig users. Like kubectl,
+ * an expired id-token is refreshed with the refresh-token against the token endpoint
+ * of the issuer, and the new tokens are written back to the kubeconfig file
+ *
+ *      date  : 2026/10/18
+ *      since : v2.0.4
+ */
+
+const (
+	oidcClientId                  = "client-id"
+	oidcClientSecret              = "client-secret"
+	oidcIdToken                   = "id-token"
+	oidcRefreshToken              = "refresh-token"
+	oidcIssuerUrl                 = "idp-issuer-url"
+	oidcCertificateAuthority      = "idp-certificate-authority"
+	oidcCertificateAuthorityData  = "idp-certificate-authority-data"
+	oidcWellKnownConfigurationUrl = "/.well-known/openid-configuration"
+
+	// the id-token is refreshed a little before it expires
+	oidcRefreshMargin = 10 * time.Second
+)
+
+type oidcAuthenticator struct {
+	http *http.Client // requests the issuer
+
+	// writes the refreshed tokens back to the kubeconfig file, the file is
+	// unknown if the Config is not resolved from LoadKubeConfig
+	kubeConfigPath string
+	userName       string
+
+	mutex   sync.Mutex
+	config  map[string]string
+	revoked bool // the server rejects the id-token before it expires
+}
+
+func newOIDCAuthenticator(config *Config) (*oidcAuthenticator, error) {
+	provider := config.AuthProvider
+	if provider.Name != "oidc" {
+		return nil, fmt.Errorf("auth-provider %s is not supported", provider.Name)
+	}
+
+	values := make(map[string]string, len(provider.Config))
+	for key, value := range provider.Config {
+		values[key] = value
+	}
+	if len(values[oidcIssuerUrl]) == 0 || len(values[oidcClientId]) == 0 {
+		return nil, fmt.Errorf("oidc auth-provider: %s and %s are required", oidcIssuerUrl, oidcClientId)
+	}
+
+	caData, err := dataOrFile(values[oidcCertificateAuthorityData], values[oidcCertificateAuthority])
+	if err != nil {
+		return nil, fmt.Errorf("oidc auth-provider: %v", err)
+	}
+
+	return &oidcAuthenticator{
+		http:           &http.Client{Transport: newTransport(newTLSConfig([][]byte{caData}, false)), Timeout: 30 * time.Second},
+		kubeConfigPath: config.kubeConfigPath,
+		userName:       config.userName,
+		config:         values,
+	}, nil
+}
+
+// Token returns the id-token, which is refreshed if it is expired
+func (authenticator *oidcAuthenticator) Token() (string, error) {
+	authenticator.mutex.Lock()
+	defer authenticator.mutex.Unlock()
+
+	idToken := authenticator.config[oidcIdToken]
+	if len(idToken) != 0 && !idTokenExpired(idToken) && !authenticator.revoked {
+		return idToken, nil
+	}
+
+	if len(authenticator.config[oidcRefreshToken]) == 0 {
+		return "", errors.New("oidc auth-provider: id-token is expired, and there is no refresh-token")
+	}
+	if err := authenticator.refresh(); err != nil {
+		return "", fmt.Errorf("oidc auth-provider: %v", err)
+	}
+	authenticator.revoked = false
+	return authenticator.config[oidcIdToken], nil
+}
+
+// invalidate forces the id-token to be refreshed on the next request,
+// it is called when the server rejects the id-token
+func (authenticator *oidcAuthenticator) invalidate() {
+	authenticator.mutex.Lock()
+	defer authenticator.mutex.Unlock()
+	authenticator.revoked = true
+}
+
+func (authenticator *oidcAuthenticator) refresh() error {
+	tokenEndpoint, err := authenticator.tokenEndpoint()
+	if err != nil {
+		return err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", authenticator.config[oidcRefreshToken])
+	req, err := http.NewRequest("POST", tokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(authenticator.config[oidcClientId]), url.QueryEscape(authenticator.config[oidcClientSecret]))
+
+	var response struct {
+		IdToken      string `json:"id_token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := authenticator.doJson(req, &response); err != nil {
+		return fmt.Errorf("refreshing token: %v", err)
+	}
+	if len(response.IdToken) == 0 {
+		return errors.New("refreshing token: id_token is missing in the response")
+	}
+
+	authenticator.config[oidcIdToken] = response.IdToken
+	if len(response.RefreshToken) != 0 {
+		// some issuers rotate the refresh token
+		authenticator.config[oidcRefreshToken] = response.RefreshToken
+	}
+
+	if len(authenticator.kubeConfigPath) == 0 {
+		return nil
+	}
+	return persistAuthProviderConfig(authenticator.kubeConfigPath, authenticator.userName, map[string]string{
+		oidcIdToken:      authenticator.config[oidcIdToken],
+		oidcRefreshToken: authenticator.config[oidcRefreshToken],
+	})
+}
+
+func (authenticator *oidcAuthenticator) tokenEndpoint() (string, error) {
+	issuer := strings.TrimSuffix(authenticator.config[oidcIssuerUrl], "/")
+	req, err := http.NewRequest("GET", issuer+oidcWellKnownConfigurationUrl, nil)
+	if err != nil {
+		return "", err
+	}
+
+	var discovery struct {
+		TokenEndpoint string `json:"token_endpoint"`
+	}
+	if err := authenticator.doJson(req, &discovery); err != nil {
+		return "", fmt.Errorf("discovering issuer %s: %v", issuer, err)
+	}
+	if len(discovery.TokenEndpoint) == 0 {
+		return "", fmt.Errorf("discovering issuer %s: token_endpoint is missing", issuer)
+	}
+	return discovery.TokenEndpoint, nil
+}
+
+func (authenticator *oidcAuthenticator) doJson(req *http.Request, value interface{}) error {
+	res, err := authenticator.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", res.Status, string(body))
+	}
+	return json.Unmarshal(body, value)
+}
+
+// idTokenExpired checks the exp claim of the JWT, the signature is verified by
+// kube-apiserver rather than the client. A malformed token is treated as expired
+func idTokenExpired(idToken string) bool {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return true
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return true
+	}
+
+	var claims struct {
+		Exp *float64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == nil {
+		return true
+	}
+	expiration := time.Unix(int64(*claims.Exp), 0)
+	return !time.Now().Add(oidcRefreshMargin).Before(expiration)
+}
+
+/************************************************************
+ *
+ *      persisting
+ *
+ *************************************************************/
+
+// persistAuthProviderConfig updates users[name=userName].user.auth-provider.config
+// of the kubeconfig file, the other content and comments are kept
+func persistAuthProviderConfig(kubeConfigPath string, userName string, values map[string]string) error {
+	info, err := os.Stat(kubeConfigPath)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(kubeConfigPath)
+	if err != nil {
+		return err
+	}
+
+	document := new(yaml.Node)
+	if err := yaml.Unmarshal(data, document); err != nil {
+		return err
+	}
+	if len(document.Content) == 0 {
+		return fmt.Errorf("kubeconfig %s is empty", kubeConfigPath)
+	}
+
+	var config *yaml.Node
+	for _, user := range mappingValue(document.Content[0], "users").Content {
+		if scalarValue(mappingValue(user, "name")) == userName {
+			config = mappingValue(mappingValue(mappingValue(user, "user"), "auth-provider"), "config")
+			break
+		}
+	}
+	if config == nil || config.Kind != yaml.MappingNode {
+		return fmt.Errorf("auth-provider config of user %s is not found in kubeconfig %s", userName, kubeConfigPath)
+	}
+
+	for key, value := range values {
+		if node := mappingValue(config, key); node.Kind == yaml.ScalarNode {
+			node.SetString(value)
+		} else {
+			keyNode, valueNode := new(yaml.Node), new(yaml.Node)
+			keyNode.SetString(key)
+			valueNode.SetString(value)
+			config.Content = append(config.Content, keyNode, valueNode)
+		}
+	}
+
+	buf := new(strings.Builder)
+	encoder := yaml.NewEncoder(buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(document); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+	return os.WriteFile(kubeConfigPath, []byte(buf.String()), info.Mode().Perm())
+}
+
+// mappingValue returns the value of key in a mapping node, or an empty node
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node != nil && node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				return node.Content[i+1]
+			}
+		}
+	}
+	return new(yaml.Node)
+}
+
+func scalarValue(node *yaml.Node) string {
+	if node.Kind == yaml.ScalarNode {
+		return node.Value
+	}
+	return ""
+}
@@ -0,0 +1,181 @@
+/**
+ * Copyright (2021, ) Institute of Software, Chinese Academy of Sciences
+ */
+
+package kubesys
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testIdToken returns an unsigned JWT expiring at exp
+func testIdToken(subject string, exp time.Time) string {
+	encode := base64.RawURLEncoding.EncodeToString
+	return encode([]byte(`{"alg":"none"}`)) + "." +
+		encode([]byte(fmt.Sprintf(`{"sub":%q,"exp":%d}`, subject, exp.Unix()))) + ".signature"
+}
+
+// testIssuer serves the discovery document and a token endpoint, which
+// exchanges refresh-token r<n> for the id-token of user<n+1> and r<n+1>
+type testIssuer struct {
+	*httptest.Server
+	mutex     sync.Mutex
+	refreshes int
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	issuer := new(testIssuer)
+	issuer.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case oidcWellKnownConfigurationUrl:
+			fmt.Fprintf(w, `{"issuer":%q,"token_endpoint":%q}`, issuer.URL, issuer.URL+"/token")
+		case "/token":
+			clientId, clientSecret, _ := r.BasicAuth()
+			if clientId != "kubectl" || clientSecret != "secret" || r.FormValue("grant_type") != "refresh_token" {
+				http.Error(w, "invalid_client", http.StatusUnauthorized)
+				return
+			}
+			issuer.mutex.Lock()
+			issuer.refreshes++
+			next := issuer.refreshes
+			issuer.mutex.Unlock()
+			if r.FormValue("refresh_token") != fmt.Sprintf("r%d", next-1) {
+				http.Error(w, "invalid_grant", http.StatusBadRequest)
+				return
+			}
+			fmt.Fprintf(w, `{"id_token":%q,"refresh_token":"r%d"}`, testIdToken(fmt.Sprintf("user%d", next), time.Now().Add(time.Hour)), next)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(issuer.Close)
+	return issuer
+}
+
+// writeOIDCKubeConfig writes a kubeconfig whose user has the given id-token and refresh-token r0
+func writeOIDCKubeConfig(t *testing.T, server string, issuer string, idToken string) string {
+	path := filepath.Join(t.TempDir(), "config")
+	kubeConfig := fmt.Sprintf(`apiVersion: v1
+kind: Config
+current-context: oidc
+clusters:
+- name: local
+  cluster:
+    server: %s
+contexts:
+- name: oidc
+  context:
+    cluster: local
+    user: oidc
+users:
+- name: oidc
+  user:
+    auth-provider:
+      name: oidc
+      config:
+        client-id: kubectl
+        client-secret: secret
+        idp-issuer-url: %s
+        id-token: %s
+        refresh-token: r0
+`, server, issuer, idToken)
+	if err := os.WriteFile(path, []byte(kubeConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// oidcServer accepts the id-tokens of the given subjects
+func oidcServer(t *testing.T, subjects ...string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, subject := range subjects {
+			token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if idTokenSubject(token) == subject {
+				fmt.Fprint(w, `{}`)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"kind":"Status","status":"Failure","reason":"Unauthorized","code":401}`)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func idTokenSubject(idToken string) string {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return ""
+	}
+	payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	var claims struct {
+		Sub string `json:"sub"`
+	}
+	json.Unmarshal(payload, &claims)
+	return claims.Sub
+}
+
+func get(t *testing.T, client *KubernetesClient, url string) int {
+	t.Helper()
+	res, err := client.http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	return res.StatusCode
+}
+
+func TestOIDCRefreshesExpiredIdToken(t *testing.T) {
+	issuer := newTestIssuer(t)
+	server := oidcServer(t, "user1")
+	path := writeOIDCKubeConfig(t, server.URL, issuer.URL, testIdToken("user0", time.Now().Add(-time.Minute)))
+
+	client, err := NewClient(WithKubeConfig(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := get(t, client, server.URL); code != http.StatusOK {
+		t.Fatalf("expected the refreshed id-token to be accepted, got %d", code)
+	}
+
+	kubeConfig, err := LoadKubeConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := kubeConfig.Users[0].User.AuthProvider.Config
+	if idTokenSubject(config[oidcIdToken]) != "user1" || config[oidcRefreshToken] != "r1" {
+		t.Fatalf("the refreshed tokens are not written back: %v", config)
+	}
+}
+
+func TestOIDCRefreshesRevokedIdToken(t *testing.T) {
+	issuer := newTestIssuer(t)
+	server := oidcServer(t, "user1")
+	path := writeOIDCKubeConfig(t, server.URL, issuer.URL, testIdToken("user0", time.Now().Add(time.Hour)))
+
+	client, err := NewClient(WithKubeConfig(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := get(t, client, server.URL); code != http.StatusUnauthorized {
+		t.Fatalf("expected the revoked id-token to be rejected, got %d", code)
+	}
+	if code := get(t, client, server.URL); code != http.StatusOK {
+		t.Fatalf("expected the refreshed id-token to be accepted, got %d", code)
+	}
+	issuer.mutex.Lock()
+	defer issuer.mutex.Unlock()
+	if issuer.refreshes != 1 {
+		t.Fatalf("expected 1 refresh, got %d", issuer.refreshes)
+	}
+}
@@ -0,0 +1,590 @@
+/**
+ * Copyright (2021, ) Institute of Software, Chinese Academy of Sciences
+ */
+
+package kubesys
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+/**
+ * this class is used for building a KubernetesClient with functional options,
+ * all the other constructors are built on it, for example
+ *
+ *      client, err := kubesys.NewClient(
+ *              kubesys.WithUrl("https://127.0.0.1:6443"),
+ *              kubesys.WithToken(token),
+ *              kubesys.WithCAData(caData),
+ *              kubesys.WithQPS(20, 50))
+ *
+ *      date  : 2026/10/18
+ *      since : v2.0.4
+ */
+
+const (
+	inClusterTokenFile  = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	inClusterRootCAFile = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+)
+
+type Option func(options *clientOptions) error
+
+type clientOptions struct {
+	url string
+
+	// credential sources, at most one of them is used
+	token             string
+	tokens            TokenSource
+	inCluster         bool
+	kubeConfig        bool
+	kubeConfigPath    string // empty means the default kubeconfig
+	kubeConfigContext string
+	config            *Config
+
+	caData       [][]byte
+	insecure     bool
+	timeout      time.Duration
+	dialTimeout  time.Duration
+	transport    http.RoundTripper
+	proxyURL     string
+	userAgent    string
+	analyzer     *KubernetesAnalyzer
+	qps          float64
+	burst        int
+	retry        *RetryPolicy
+	noRetry      bool
+	namespace    string
+	impersonate  *ImpersonationConfig
+	interceptors []Interceptor
+	metrics      *Metrics
+	tracer       Tracer
+}
+
+/************************************************************
+ *
+ *      options
+ *
+ *************************************************************/
+
+// WithUrl sets the url of kube-apiserver, it overrides the server of kubeconfig.
+// Besides https, it can be http such as kubectl proxy, or unix:///path/to/socket
+func WithUrl(url string) Option {
+	return func(options *clientOptions) error {
+		options.url = url
+		return nil
+	}
+}
+
+// WithToken uses a bearer token
+func WithToken(token string) Option {
+	return func(options *clientOptions) error {
+		if err := validateToken(token); err != nil {
+			return err
+		}
+		options.token = token
+		return nil
+	}
+}
+
+// WithTokenFile uses the bearer token in a file, which is reloaded after it is rotated
+func WithTokenFile(path string) Option {
+	return func(options *clientOptions) error {
+		source := newFileTokenSource(path)
+		token, err := source.Token()
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidToken, err)
+		}
+		options.token = token
+		options.tokens = source
+		return nil
+	}
+}
+
+// WithTokenSource gets the bearer token of every request from source
+func WithTokenSource(source TokenSource) Option {
+	return func(options *clientOptions) error {
+		if source == nil {
+			return fmt.Errorf("%w, token source is nil", ErrInvalidToken)
+		}
+		options.tokens = source
+		return nil
+	}
+}
+
+// WithInCluster uses the service account kubernetes gives to pods
+func WithInCluster() Option {
+	return func(options *clientOptions) error {
+		options.inCluster = true
+		return nil
+	}
+}
+
+// WithKubeConfig uses the current-context of the kubeConfig file
+func WithKubeConfig(kubeConfig string) Option {
+	return WithKubeConfigContext(kubeConfig, "")
+}
+
+// WithKubeConfigContext uses the named context of the kubeConfig file,
+// an empty contextName means the current-context
+func WithKubeConfigContext(kubeConfig string, contextName string) Option {
+	return func(options *clientOptions) error {
+		if len(kubeConfig) == 0 {
+			return fmt.Errorf("%w, the path is empty", ErrInvalidKubeConfig)
+		}
+		options.kubeConfig = true
+		options.kubeConfigPath = kubeConfig
+		options.kubeConfigContext = contextName
+		return nil
+	}
+}
+
+// WithDefaultKubeConfig follows kubectl's rules to find the kubeconfig,
+// see NewKubernetesClientWithDefaultKubeConfig
+func WithDefaultKubeConfig() Option {
+	return func(options *clientOptions) error {
+		options.kubeConfig = true
+		options.kubeConfigPath = ""
+		options.kubeConfigContext = ""
+		return nil
+	}
+}
+
+// WithConfig uses a resolved kubeconfig context
+func WithConfig(config *Config) Option {
+	return func(options *clientOptions) error {
+		if config == nil {
+			return fmt.Errorf("%w, config is nil", ErrInvalidKubeConfig)
+		}
+		options.config = config
+		return nil
+	}
+}
+
+// WithCAData verifies the server certificate with the PEM encoded CA bundles, which are
+// ignored if the cluster of kubeconfig has a certificate-authority or insecure-skip-tls-verify
+func WithCAData(caData ...[]byte) Option {
+	return func(options *clientOptions) error {
+		options.caData = append(options.caData, caData...)
+		return nil
+	}
+}
+
+// WithCAFile verifies the server certificate with the PEM encoded CA bundle in a file
+func WithCAFile(path string) Option {
+	return func(options *clientOptions) error {
+		caData, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		options.caData = append(options.caData, caData)
+		return nil
+	}
+}
+
+// WithInsecureSkipVerify does not verify the server certificate, which is vulnerable to
+// man-in-the-middle attacks, and should only be used for testing. It is ignored if the
+// cluster of kubeconfig has a certificate-authority
+func WithInsecureSkipVerify() Option {
+	return func(options *clientOptions) error {
+		options.insecure = true
+		return nil
+	}
+}
+
+// WithTimeout limits the time of each request, watches are not limited
+func WithTimeout(timeout time.Duration) Option {
+	return func(options *clientOptions) error {
+		if timeout < 0 {
+			return errors.New("timeout must not be negative")
+		}
+		options.timeout = timeout
+		return nil
+	}
+}
+
+// WithDialTimeout limits the time of connecting to the server
+func WithDialTimeout(timeout time.Duration) Option {
+	return func(options *clientOptions) error {
+		if timeout < 0 {
+			return errors.New("dial timeout must not be negative")
+		}
+		options.dialTimeout = timeout
+		return nil
+	}
+}
+
+// WithTransport sends the requests with transport, the CA, insecure, dial timeout, proxy
+// and Unix domain socket settings are ignored since they are a part of the transport. The
+// transport is never changed, a copy of it is used for the client certificates of exec plugins
+func WithTransport(transport http.RoundTripper) Option {
+	return func(options *clientOptions) error {
+		if transport == nil {
+			return errors.New("transport is nil")
+		}
+		options.transport = transport
+		return nil
+	}
+}
+
+// WithProxy sends the requests and watches through an http, https or socks5 proxy,
+// it overrides the proxy-url of kubeconfig, and HTTPS_PROXY and NO_PROXY are used by default
+func WithProxy(proxyURL string) Option {
+	return func(options *clientOptions) error {
+		if _, err := proxyFor(proxyURL); err != nil {
+			return err
+		}
+		options.proxyURL = proxyURL
+		return nil
+	}
+}
+
+// WithUserAgent sets the User-Agent header of every request
+func WithUserAgent(userAgent string) Option {
+	return func(options *clientOptions) error {
+		options.userAgent = userAgent
+		return nil
+	}
+}
+
+// WithAnalyzer reuses an analyzer, so that the resources are not discovered again
+func WithAnalyzer(analyzer *KubernetesAnalyzer) Option {
+	return func(options *clientOptions) error {
+		if analyzer == nil {
+			return errors.New("analyzer is nil")
+		}
+		options.analyzer = analyzer
+		return nil
+	}
+}
+
+// WithRuleBase reuses a RuleBase, so that the resources are not discovered again
+func WithRuleBase(ruleBase *RuleBase) Option {
+	return func(options *clientOptions) error {
+		if ruleBase == nil {
+			return errors.New("rule base is nil")
+		}
+		options.analyzer = &KubernetesAnalyzer{
+			RuleBase: ruleBase,
+			Registry: NewRegistry(ruleBase),
+		}
+		return nil
+	}
+}
+
+// WithQPS limits the requests per second, with bursts of at most burst requests. Discovery,
+// retries and the setup of watches count against it, and waiting stops with the context
+func WithQPS(qps float64, burst int) Option {
+	return func(options *clientOptions) error {
+		if qps <= 0 || burst <= 0 {
+			return errors.New("qps and burst must be positive")
+		}
+		options.qps = qps
+		options.burst = burst
+		return nil
+	}
+}
+
+// WithRetryPolicy replaces DefaultRetryPolicy, nil disables retrying
+func WithRetryPolicy(policy *RetryPolicy) Option {
+	return func(options *clientOptions) error {
+		if policy == nil {
+			options.retry, options.noRetry = nil, true
+			return nil
+		}
+		if policy.MaxAttempts < 1 || policy.InitialBackoff < 0 || policy.MaxBackoff < 0 {
+			return errors.New("max attempts must be positive, and backoffs must not be negative")
+		}
+		copied := *policy
+		copied.RetryableCodes = append([]int(nil), policy.RetryableCodes...)
+		options.retry, options.noRetry = &copied, false
+		return nil
+	}
+}
+
+// WithInterceptors adds interceptors to the client, see KubernetesClient.Use
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(options *clientOptions) error {
+		options.interceptors = append(options.interceptors, interceptors...)
+		return nil
+	}
+}
+
+// WithMetrics records the metrics of the client into the given one, which may be shared by clients
+func WithMetrics(metrics *Metrics) Option {
+	return func(options *clientOptions) error {
+		if metrics == nil {
+			return errors.New("metrics is nil")
+		}
+		options.metrics = metrics
+		return nil
+	}
+}
+
+// WithTracer starts a span around every request, see Tracer
+func WithTracer(tracer Tracer) Option {
+	return func(options *clientOptions) error {
+		options.tracer = tracer
+		return nil
+	}
+}
+
+// WithNamespace sets the default namespace of the operations on one object, it overrides
+// the namespace of kubeconfig. Lists and watches of a kind are not defaulted
+func WithNamespace(namespace string) Option {
+	return func(options *clientOptions) error {
+		options.namespace = namespace
+		return nil
+	}
+}
+
+// WithImpersonation acts as another user, it overrides the as, as-uid, as-groups
+// and as-user-extra of kubeconfig
+func WithImpersonation(config ImpersonationConfig) Option {
+	return func(options *clientOptions) error {
+		if err := config.validate(); err != nil {
+			return err
+		}
+		options.impersonate = config.deepCopy()
+		return nil
+	}
+}
+
+/************************************************************
+ *
+ *      building
+ *
+ *************************************************************/
+
+func NewClient(opts ...Option) (*KubernetesClient, error) {
+	options := new(clientOptions)
+	for _, opt := range opts {
+		if err := opt(options); err != nil {
+			return nil, err
+		}
+	}
+
+	config, err := options.resolveConfig()
+	if err != nil {
+		return nil, err
+	}
+	if options.kubeConfig || options.config != nil {
+		config = options.withServerCA(config)
+	}
+
+	rawUrl := config.Server
+	if len(options.url) != 0 {
+		rawUrl = options.url
+	}
+	url, socket, err := parseUrl(rawUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient, err := options.httpClient(config, socket)
+	if err != nil {
+		return nil, err
+	}
+
+	analyzer := options.analyzer
+	if analyzer == nil {
+		analyzer = NewKubernetesAnalyzer()
+	}
+
+	client := createClient(url, options.token, httpClient, analyzer)
+	client.tokens = options.tokens
+	client.Namespace = config.Namespace
+	if len(options.namespace) != 0 {
+		client.Namespace = options.namespace
+	}
+	if len(socket) == 0 {
+		client.cluster = options.clusterConfig(url, config)
+	}
+	client.userAgent = options.userAgent
+	client.timeout = options.timeout
+	if options.qps > 0 {
+		client.limiter = newRateLimiter(options.qps, options.burst)
+	}
+	client.retry = options.retry
+	client.Use(options.interceptors...)
+	if options.metrics != nil {
+		client.metrics = options.metrics
+	}
+	client.tracer = options.tracer
+	if client.retry == nil && !options.noRetry {
+		client.retry = DefaultRetryPolicy()
+	}
+
+	if options.impersonate != nil {
+		client.impersonate = options.impersonate
+	} else {
+		if err := config.Impersonate.validate(); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidKubeConfig, err)
+		}
+		if len(config.Impersonate.UserName) != 0 {
+			client.impersonate = config.Impersonate.deepCopy()
+		}
+	}
+	return client, nil
+}
+
+// resolveConfig returns the kubeconfig context, or an empty Config if
+// the client is not built from kubeconfig
+func (options *clientOptions) resolveConfig() (*Config, error) {
+	sources := 0
+	for _, used := range []bool{len(options.token) != 0 || options.tokens != nil,
+		options.inCluster, options.kubeConfig, options.config != nil} {
+		if used {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return nil, errors.New("only one of token, in-cluster, kubeconfig and config can be used")
+	}
+
+	if options.inCluster {
+		return options.inClusterConfig()
+	}
+
+	if options.config != nil {
+		return options.config, nil
+	}
+
+	if options.kubeConfig {
+		var kubeConfig *KubeConfig
+		var err error
+		if len(options.kubeConfigPath) == 0 {
+			kubeConfig, err = LoadKubeConfigs(DefaultKubeConfigPaths()...)
+			if errors.Is(err, os.ErrNotExist) {
+				// like kubectl, fall back to in-cluster
+				options.kubeConfig = false
+				return options.inClusterConfig()
+			}
+		} else {
+			kubeConfig, err = LoadKubeConfig(options.kubeConfigPath)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidKubeConfig, err)
+		}
+
+		config, err := kubeConfig.ToConfig(options.kubeConfigContext)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidKubeConfig, err)
+		}
+		return config, nil
+	}
+	return new(Config), nil
+}
+
+// inClusterConfig uses the service account token, which is put into
+// KubernetesClient.Token rather than the Config to be reloaded by createRequest
+func (options *clientOptions) inClusterConfig() (*Config, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if len(host) == 0 || len(port) == 0 {
+		return nil, fmt.Errorf("%w, KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT must be defined", ErrInCluster)
+	}
+
+	if err := WithTokenFile(inClusterTokenFile)(options); err != nil {
+		return nil, err
+	}
+
+	caData, err := os.ReadFile(inClusterRootCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInCluster, err)
+	}
+	options.caData = append(options.caData, caData)
+
+	return &Config{Server: "https://" + net.JoinHostPort(host, port)}, nil
+}
+
+// withServerCA returns a copy of config with the CA options, if the cluster
+// of kubeconfig has neither a CA nor insecure-skip-tls-verify
+func (options *clientOptions) withServerCA(config *Config) *Config {
+	if len(options.caData) == 0 && !options.insecure || config.Insecure ||
+		len(config.CertificateAuthorityData) != 0 || len(config.CertificateAuthority) != 0 {
+		return config
+	}
+	copied := *config
+	copied.Insecure = options.insecure
+	if len(options.caData) != 0 {
+		copied.CertificateAuthorityData = base64.StdEncoding.EncodeToString(bytes.Join(options.caData, []byte("\n")))
+	}
+	return &copied
+}
+
+// clusterConfig returns how the client reaches the server, the CA is empty
+// if the system CAs are used or the server certificate is not verified
+func (options *clientOptions) clusterConfig(url string, config *Config) *Config {
+	cluster := &Config{Server: url, ProxyURL: config.ProxyURL, TLSServerName: config.TLSServerName}
+	if len(options.proxyURL) != 0 {
+		cluster.ProxyURL = options.proxyURL
+	}
+
+	var caData []byte
+	if options.kubeConfig || options.config != nil {
+		cluster.Insecure = config.Insecure
+		caData, _ = config.caData()
+	} else {
+		cluster.Insecure = options.insecure
+		caData = bytes.Join(options.caData, []byte("\n"))
+	}
+	if !cluster.Insecure && len(caData) != 0 {
+		cluster.CertificateAuthorityData = base64.StdEncoding.EncodeToString(caData)
+	}
+	return cluster
+}
+
+func (options *clientOptions) httpClient(config *Config, socket string) (*http.Client, error) {
+	transport := options.transport
+	if transport == nil {
+		var tlsConfig *tls.Config
+		var err error
+		if options.kubeConfig || options.config != nil {
+			if tlsConfig, err = TLSConfigFor(config); err != nil {
+				return nil, fmt.Errorf("%w: %w", ErrInvalidKubeConfig, err)
+			}
+		} else {
+			tlsConfig = newTLSConfig(options.caData, options.insecure)
+		}
+		dialer := newDialer()
+		if options.dialTimeout > 0 {
+			dialer.Timeout = options.dialTimeout
+		}
+		httpTransport := newTransport(tlsConfig)
+		httpTransport.DialContext = dialer.DialContext
+
+		proxyURL := config.ProxyURL
+		if len(options.proxyURL) != 0 {
+			proxyURL = options.proxyURL
+		}
+		if len(proxyURL) != 0 {
+			if httpTransport.Proxy, err = proxyFor(proxyURL); err != nil {
+				return nil, fmt.Errorf("%w: %w", ErrInvalidKubeConfig, err)
+			}
+		}
+
+		if len(socket) != 0 {
+			// the host of the requests is ignored
+			httpTransport.Proxy = nil
+			httpTransport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, "unix", socket)
+			}
+		}
+		transport = httpTransport
+	}
+
+	// the credentials of kubeconfig users
+	roundTripper, err := authRoundTripperFor(config, transport)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidKubeConfig, err)
+	}
+	return &http.Client{Transport: roundTripper}, nil
+}
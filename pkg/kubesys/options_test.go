@@ -0,0 +1,63 @@
+/**
+ * Copyright (2021, ) Institute of Software, Chinese Academy of Sciences
+ */
+
+package kubesys
+
+import (
+	"encoding/base64"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestConfigWithoutCAUsesCAOptions(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	caData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	tests := []struct {
+		name   string
+		option Option
+		caData []byte
+	}{
+		{name: "ca data", option: WithCAData(caData), caData: caData},
+		{name: "insecure", option: WithInsecureSkipVerify()},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := &Config{Server: server.URL}
+			client, err := NewClient(WithConfig(config), test.option)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := client.http.Get(server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+			if client.cluster.CertificateAuthorityData != base64.StdEncoding.EncodeToString(test.caData) {
+				t.Fatalf("unexpected CA %q", client.cluster.CertificateAuthorityData)
+			}
+			if client.cluster.Insecure != (test.caData == nil) {
+				t.Fatalf("unexpected insecure %v", client.cluster.Insecure)
+			}
+			if config.Insecure || len(config.CertificateAuthorityData) != 0 {
+				t.Fatal("the given config is changed")
+			}
+		})
+	}
+}
+
+func TestConfigWithCAIgnoresInsecure(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// reading the certificate-authority fails, rather than being skipped
+	config := &Config{Server: server.URL, CertificateAuthority: "/nonexistent/ca.crt"}
+
+	if _, err := NewClient(WithConfig(config), WithInsecureSkipVerify()); err == nil {
+		t.Fatal("expected the certificate-authority of kubeconfig to be used")
+	}
+}
@@ -0,0 +1,77 @@
+/**
+ * Copyright (2021, ) Institute of Software, Chinese Academy of Sciences
+ */
+
+package kubesys
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+/**
+ * this class is used for limiting the requests per second with a token bucket,
+ * which holds at most burst tokens and is refilled at qps tokens per second.
+ * Requests, discovery and the setup of watches all wait on it
+ *
+ *      date  : 2026/10/18
+ *      since : v2.0.4
+ */
+
+type rateLimiter struct {
+	qps   float64
+	burst float64
+
+	mutex  sync.Mutex
+	tokens float64 // negative means the requests waiting for tokens
+	last   time.Time
+}
+
+func newRateLimiter(qps float64, burst int) *rateLimiter {
+	return &rateLimiter{
+		qps:    qps,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// reserve takes a token, and returns how long to wait until it is available
+func (limiter *rateLimiter) reserve() time.Duration {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	now := time.Now()
+	limiter.tokens += now.Sub(limiter.last).Seconds() * limiter.qps
+	if limiter.tokens > limiter.burst {
+		limiter.tokens = limiter.burst
+	}
+	limiter.last = now
+
+	limiter.tokens--
+	if limiter.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-limiter.tokens / limiter.qps * float64(time.Second))
+}
+
+// wait blocks until a token is available, or returns the error of the context
+// if it is done first, then the token is given back for the other requests
+func (limiter *rateLimiter) wait(ctx context.Context) error {
+	delay := limiter.reserve()
+	if delay <= 0 {
+		return nil
+	}
+	if err := sleep(ctx, delay); err != nil {
+		limiter.cancel()
+		return err
+	}
+	return nil
+}
+
+func (limiter *rateLimiter) cancel() {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	limiter.tokens++
+}
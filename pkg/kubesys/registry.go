@@ -5,8 +5,10 @@
 package kubesys
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 )
 
 /**
@@ -27,39 +29,50 @@ func NewRegistry(ruleBase *RuleBase) *Registry {
 	return registry
 }
 
-func register(client *KubernetesClient, url string, registry *Registry) {
+func register(ctx context.Context, client *KubernetesClient, url string, registry *Registry) error {
 
-	resourceRequest, err := client.createRequest("GET", url, nil)
+	// such as apps/v1, or v1 for the core group
+	groupVersion := strings.TrimPrefix(strings.TrimPrefix(strings.TrimPrefix(url, client.Url), "/api/"), "/apis/")
+
+	resourceRequest, err := client.createRequest(ctx, "GET", url, nil)
 	if err != nil {
-		fmt.Println("createRequest error", err)
-		panic(err)
+		return fmt.Errorf("%w for group %s: %w", ErrDiscovery, groupVersion, err)
 	}
 
 	resourceStringValues, err := client.doRequest(resourceRequest)
-
 	if err != nil {
-		fmt.Println("request resource string values error", err)
-		panic(err)
+		return fmt.Errorf("%w for group %s: %w", ErrDiscovery, groupVersion, err)
 	}
+
 	resourceValues := make(map[string]interface{})
-	json.Unmarshal([]byte(resourceStringValues), &resourceValues)
+	if err := json.Unmarshal(resourceStringValues, &resourceValues); err != nil {
+		return fmt.Errorf("%w for group %s: %w", ErrDiscovery, groupVersion, err)
+	}
 
-	apiVersion := resourceValues["groupVersion"].(string)
-	for _, w := range resourceValues["resources"].([]interface{}) {
-		resourceValue := w.(map[string]interface{})
-		shortKind := resourceValue["kind"].(string)
+	apiVersion, ok := resourceValues["groupVersion"].(string)
+	if !ok {
+		return fmt.Errorf("%w for group %s: groupVersion is missing", ErrDiscovery, groupVersion)
+	}
+	resources, _ := resourceValues["resources"].([]interface{})
+	for _, w := range resources {
+		resourceValue, ok := w.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		shortKind, _ := resourceValue["kind"].(string)
 		fullKind := getFullKind(resourceValue, shortKind, apiVersion)
 
 		if _, ok := registry.RuleBase.FullKindToApiPrefixMapper[fullKind]; !ok {
 			registry.RuleBase.KindToFullKindMapper[shortKind] = append(registry.RuleBase.KindToFullKindMapper[shortKind], fullKind)
 			registry.RuleBase.FullKindToApiPrefixMapper[fullKind] = url
 
-			registry.RuleBase.FullKindToNameMapper[fullKind] = resourceValue["name"].(string)
-			registry.RuleBase.FullKindToNamespaceMapper[fullKind] = resourceValue["namespaced"].(bool)
+			registry.RuleBase.FullKindToNameMapper[fullKind], _ = resourceValue["name"].(string)
+			registry.RuleBase.FullKindToNamespaceMapper[fullKind], _ = resourceValue["namespaced"].(bool)
 
 			registry.RuleBase.FullKindToVersionMapper[fullKind] = apiVersion
 			registry.RuleBase.FullKindToGroupMapper[fullKind] = getGroup(apiVersion)
 			registry.RuleBase.FullKindToVerbsMapper[fullKind] = resourceValue["verbs"]
 		}
 	}
+	return nil
 }
@@ -0,0 +1,43 @@
+/**
+ * Copyright (2021, ) Institute of Software, Chinese Academy of Sciences
+ */
+
+package kubesys
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+/**
+ * this class is used for telling which operation a request belongs to, the
+ * info is carried by the context of the request, so that interceptors and
+ * metrics can see it
+ *
+ *      date  : 2026/10/18
+ *      since : v2.0.4
+ */
+
+type RequestInfo struct {
+	Verb        string // such as get, list, watch, create, update and delete
+	FullKind    string // empty for discovery
+	Namespace   string
+	Name        string
+	Subresource string // such as status, binding and token
+}
+
+type requestInfoKey struct{}
+
+func withRequestInfo(ctx context.Context, info RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, info)
+}
+
+// RequestInfoFrom returns the info of the request, which is made up
+// from the method if the request is not sent by an operation of the client
+func RequestInfoFrom(request *http.Request) RequestInfo {
+	if info, ok := request.Context().Value(requestInfoKey{}).(RequestInfo); ok {
+		return info
+	}
+	return RequestInfo{Verb: strings.ToLower(request.Method)}
+}
@@ -0,0 +1,171 @@
+/**
+ * Copyright (2021, ) Institute of Software, Chinese Academy of Sciences
+ */
+
+package kubesys
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+/**
+ * this class is used for retrying the requests failed with transient errors,
+ * such as 429 Too Many Requests and connection resets during API server rollouts.
+ * The backoff is exponential with jitter, and Retry-After of the server is honored
+ *
+ *      date  : 2026/10/18
+ *      since : v2.0.4
+ */
+
+type RetryPolicy struct {
+	MaxAttempts    int           // including the first one, 1 disables retrying
+	InitialBackoff time.Duration // doubled on every retry
+	MaxBackoff     time.Duration // the upper limit of the backoff, Retry-After is not limited
+
+	// the status codes that are retried. The requests changing objects are only retried on 429
+	// or with Retry-After, because the others may be returned after the change has been applied
+	RetryableCodes []int
+
+	// reports whether an error without response is retried, isRetryableError by default. The
+	// requests changing objects are only retried if the connection is refused, so they are never sent
+	RetryableError func(err error) bool
+}
+
+// DefaultRetryPolicy is used by the clients unless WithRetryPolicy is given
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    4,
+		InitialBackoff: 200 * time.Millisecond,
+		MaxBackoff:     10 * time.Second,
+		RetryableCodes: []int{
+			http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// backoff reports whether the failed attempt is retried, and how long to wait before it
+func (policy *RetryPolicy) backoff(method string, attempt int, res *http.Response, err error) (time.Duration, bool) {
+	if policy == nil || attempt >= policy.MaxAttempts {
+		return 0, false
+	}
+
+	// a retried PUT or DELETE fails with Conflict or NotFound if the first one has been applied
+	readOnly := method == http.MethodGet || method == http.MethodHead
+
+	var status *StatusError
+	if errors.As(err, &status) {
+		retryable := false
+		for _, code := range policy.RetryableCodes {
+			retryable = retryable || code == status.Code
+		}
+		if !retryable {
+			return 0, false
+		}
+		delay, ok := retryAfter(res, status)
+		if !readOnly && !ok && status.Code != http.StatusTooManyRequests {
+			return 0, false
+		}
+		if ok {
+			return delay, true
+		}
+	} else {
+		if !readOnly && !errors.Is(err, syscall.ECONNREFUSED) {
+			return 0, false
+		}
+		retryableError := policy.RetryableError
+		if retryableError == nil {
+			retryableError = isRetryableError
+		}
+		if !retryableError(err) {
+			return 0, false
+		}
+	}
+
+	delay := policy.InitialBackoff << (attempt - 1)
+	if delay <= 0 || policy.MaxBackoff > 0 && delay > policy.MaxBackoff {
+		delay = policy.MaxBackoff
+	}
+	// a random delay in [delay/2, delay), so that the clients do not retry at the same time
+	if delay > 1 {
+		delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+	}
+	return delay, true
+}
+
+// retryAfter reads the Retry-After header in seconds or as an HTTP date,
+// or the retryAfterSeconds of the Status body
+func retryAfter(res *http.Response, status *StatusError) (time.Duration, bool) {
+	var value string
+	if res != nil {
+		value = res.Header.Get("Retry-After")
+	}
+	if len(value) != 0 {
+		if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second, true
+		}
+		if date, err := http.ParseTime(value); err == nil {
+			if delay := time.Until(date); delay > 0 {
+				return delay, true
+			}
+			return 0, true
+		}
+	}
+	if status.Details != nil && status.Details.RetryAfterSeconds > 0 {
+		return time.Duration(status.Details.RetryAfterSeconds) * time.Second, true
+	}
+	return 0, false
+}
+
+// isRetryableError checks the connection errors, the cancelled and timed
+// out requests are not retried, because their contexts are done
+func isRetryableError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// sleep waits for the delay, or returns the error of the context if it is done first
+func sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// rewindRequest returns a copy of the sent request with a new body
+func rewindRequest(request *http.Request) (*http.Request, error) {
+	request = request.Clone(request.Context())
+	if request.Body == nil || request.Body == http.NoBody {
+		return request, nil
+	}
+	if request.GetBody == nil {
+		return nil, errors.New("the request body cannot be sent again")
+	}
+	body, err := request.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	request.Body = body
+	return request, nil
+}
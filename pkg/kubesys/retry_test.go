@@ -0,0 +1,44 @@
+/**
+ * Copyright (2021, ) Institute of Software, Chinese Academy of Sciences
+ */
+
+package kubesys
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"syscall"
+	"testing"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	retryAfter := &http.Response{Header: http.Header{"Retry-After": []string{"1"}}}
+	tests := []struct {
+		method string
+		res    *http.Response
+		err    error
+		retry  bool
+	}{
+		{method: "GET", err: &StatusError{Code: 500}, retry: true},
+		{method: "GET", err: &StatusError{Code: 504}, retry: true},
+		{method: "GET", err: fmt.Errorf("request error: %w", io.EOF), retry: true},
+		{method: "GET", err: &StatusError{Code: 404}, retry: false},
+		{method: "POST", err: &StatusError{Code: 429}, retry: true},
+		{method: "POST", err: &StatusError{Code: 500}, retry: false},
+		{method: "PUT", err: &StatusError{Code: 500}, retry: false},
+		{method: "PUT", err: &StatusError{Code: 504}, retry: false},
+		{method: "PUT", res: retryAfter, err: &StatusError{Code: 503}, retry: true},
+		{method: "DELETE", err: &StatusError{Code: 500}, retry: false},
+		{method: "DELETE", err: fmt.Errorf("request error: %w", io.EOF), retry: false},
+		{method: "DELETE", err: fmt.Errorf("request error: %w", syscall.ECONNRESET), retry: false},
+		{method: "DELETE", err: fmt.Errorf("request error: %w", syscall.ECONNREFUSED), retry: true},
+		{method: "PATCH", err: &StatusError{Code: 429}, retry: true},
+	}
+	for _, test := range tests {
+		_, retry := DefaultRetryPolicy().backoff(test.method, 1, test.res, test.err)
+		if retry != test.retry {
+			t.Errorf("%s with %v: expected retry %v, got %v", test.method, test.err, test.retry, retry)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+/**
+ * Copyright (2021, ) Institute of Software, Chinese Academy of Sciences
+ */
+
+package kubesys
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/tidwall/gjson"
+	"strings"
+	"time"
+)
+
+/**
+ * this class is used for requesting bound service account tokens through
+ * the TokenRequest API, see
+ * https://kubernetes.io/docs/reference/kubernetes-api/authentication-resources/token-request-v1/
+ *
+ *      date  : 2026/10/18
+ *      since : v2.0.4
+ */
+
+type TokenRequest struct {
+	Audiences         []string // optional, the audiences of the API server are used if empty
+	ExpirationSeconds int64    // optional, the server defaults it to one hour, the minimum is 10 minutes
+}
+
+type ServiceAccountToken struct {
+	Token               string
+	ExpirationTimestamp time.Time
+}
+
+// CreateServiceAccountToken requests a token of the service account through its token subresource,
+// an empty namespace means the default namespace of the client
+func (client *KubernetesClient) CreateServiceAccountToken(namespace string, name string, request TokenRequest) (*ServiceAccountToken, error) {
+	return client.CreateServiceAccountTokenWithContext(context.Background(), namespace, name, request)
+}
+
+func (client *KubernetesClient) CreateServiceAccountTokenWithContext(ctx context.Context, namespace string, name string, request TokenRequest) (*ServiceAccountToken, error) {
+	if len(name) == 0 {
+		return nil, errors.New("service account name is required")
+	}
+	namespace = client.namespaceOrDefault(namespace)
+
+	spec := map[string]interface{}{}
+	if len(request.Audiences) != 0 {
+		spec["audiences"] = request.Audiences
+	}
+	if request.ExpirationSeconds > 0 {
+		spec["expirationSeconds"] = request.ExpirationSeconds
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"apiVersion": "authentication.k8s.io/v1",
+		"kind":       "TokenRequest",
+		"spec":       spec,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	url := client.Url + "/api/v1/namespaces/" + namespace + "/serviceaccounts/" + name + "/token"
+	ctx = withRequestInfo(ctx, RequestInfo{Verb: "create", FullKind: "ServiceAccount", Namespace: namespace, Name: name, Subresource: "token"})
+	req, err := client.createRequest(ctx, "POST", url, strings.NewReader(string(body)))
+	if err != nil {
+		return nil, err
+	}
+	value, err := client.doRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("requesting token of service account %s/%s: %w", namespace, name, err)
+	}
+
+	status := gjson.GetBytes(value, "status")
+	token := &ServiceAccountToken{Token: status.Get("token").String()}
+	if len(token.Token) == 0 {
+		return nil, fmt.Errorf("requesting token of service account %s/%s: token is missing in the response", namespace, name)
+	}
+	if expiration := status.Get("expirationTimestamp").String(); len(expiration) != 0 {
+		if token.ExpirationTimestamp, err = time.Parse(time.RFC3339, expiration); err != nil {
+			return nil, err
+		}
+	}
+	return token, nil
+}
+
+// ServiceAccountKubeConfig requests a token of the service account, and returns a kubeconfig
+// which uses it against the server of the client, with the CA, TLS server name and proxy of the client.
+// The cluster, user and context are all named name, and the context uses the given namespace. A client
+// connected through a unix socket cannot issue kubeconfigs
+func (client *KubernetesClient) ServiceAccountKubeConfig(namespace string, name string, request TokenRequest) (*KubeConfig, error) {
+	return client.ServiceAccountKubeConfigWithContext(context.Background(), namespace, name, request)
+}
+
+func (client *KubernetesClient) ServiceAccountKubeConfigWithContext(ctx context.Context, namespace string, name string, request TokenRequest) (*KubeConfig, error) {
+	namespace = client.namespaceOrDefault(namespace)
+	config, err := client.clusterConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := client.CreateServiceAccountTokenWithContext(ctx, namespace, name, request)
+	if err != nil {
+		return nil, err
+	}
+
+	config.Token = token.Token
+	config.Namespace = namespace
+	return NewKubeConfigFor(config, name), nil
+}
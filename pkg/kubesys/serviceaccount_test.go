@@ -0,0 +1,56 @@
+/**
+ * Copyright (2021, ) Institute of Software, Chinese Academy of Sciences
+ */
+
+package kubesys
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServiceAccountKubeConfigKeepsCluster(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/api/v1/namespaces/kube-system/serviceaccounts/reader/token" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"kind":"TokenRequest","status":{"token":"sa-token","expirationTimestamp":"2030-01-01T00:00:00Z"}}`)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(WithConfig(&Config{Server: server.URL + "/", TLSServerName: "kubernetes.default"}),
+		WithProxy("socks5://127.0.0.1:1080"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the test server is reached directly
+	client.http = server.Client()
+
+	kubeConfig, err := client.ServiceAccountKubeConfig("kube-system", "reader", TokenRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cluster := kubeConfig.Clusters[0].Cluster
+	if cluster.Server != server.URL || cluster.TLSServerName != "kubernetes.default" || cluster.ProxyURL != "socks5://127.0.0.1:1080" {
+		t.Fatalf("unexpected cluster %+v", cluster)
+	}
+	if kubeConfig.Users[0].User.Token != "sa-token" || kubeConfig.Contexts[0].Context.Namespace != "kube-system" {
+		t.Fatalf("unexpected kubeconfig %+v", kubeConfig)
+	}
+}
+
+func TestUnixSocketClientCannotIssueKubeConfigs(t *testing.T) {
+	client, err := NewClient(WithUrl("unix:///var/run/kube-apiserver.sock"), WithToken("token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.ServiceAccountKubeConfig("default", "reader", TokenRequest{}); err == nil {
+		t.Fatal("expected an error for the unix socket")
+	}
+	if _, err := client.IssueClientCertificate(CertificateRequest{UserName: "alice"}); err == nil {
+		t.Fatal("expected an error for the unix socket")
+	}
+}
@@ -5,16 +5,16 @@
 package kubesys
 
 import (
-	"bufio"
+	"bytes"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/base64"
 	"errors"
-	"io"
+	"fmt"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
-	"strings"
 	"time"
 )
 
@@ -28,49 +28,42 @@ import (
  */
 
 type Config struct {
-	Server                   string
+	Server        string
+	ProxyURL      string // http, https or socks5 proxy, HTTPS_PROXY and NO_PROXY are used if empty
+	TLSServerName string
+	Insecure      bool // skips verifying the server certificate
+
+	// the base64 encoded *Data fields take precedence over the file paths
+	ClientCertificate        string
 	ClientCertificateData    string
+	ClientKey                string
 	ClientKeyData            string
+	CertificateAuthority     string
 	CertificateAuthorityData string
+
+	// TokenFile takes precedence over the bearer token, which is used if the file cannot be read
+	Token     string
+	TokenFile string
+	Username  string
+	Password  string
+
+	Namespace    string
+	Exec         *ExecConfig
+	AuthProvider *AuthProviderConfig
+	Impersonate  ImpersonationConfig
+
+	// where the refreshed tokens of AuthProvider are written back
+	kubeConfigPath string
+	userName       string
 }
 
+// NewForConfig resolves the current-context of the given kubeconfig file
 func NewForConfig(kubeConfig string) (*Config, error) {
-	f, err := os.Open(kubeConfig)
+	config, err := LoadKubeConfig(kubeConfig)
 	if err != nil {
-		f.Close()
 		return nil, err
 	}
-	config := new(Config)
-	buf := bufio.NewReader(f)
-	n := 0
-	for {
-		b, err := buf.ReadBytes('\n')
-		if err != nil && err != io.EOF {
-			f.Close()
-			return nil, errors.New("kubeconfig file error")
-		}
-		s := strings.Replace(string(b), " ", "", -1)
-		if s == "" {
-			break
-		} else if strings.Contains(s, "server:") {
-			config.Server = strings.Replace(strings.Split(s, "server:")[1], "\r\n", "", -1)
-			n++
-		} else if strings.Contains(s, "client-certificate-data:") {
-			config.ClientCertificateData = strings.Replace(strings.Split(s, "client-certificate-data:")[1], "\n", "", -1)
-			n++
-		} else if strings.Contains(s, "client-key-data:") {
-			config.ClientKeyData = strings.Replace(strings.Split(s, "client-key-data:")[1], "\n", "", -1)
-			n++
-		} else if strings.Contains(s, "certificate-authority-data:") {
-			config.CertificateAuthorityData = strings.Replace(strings.Split(s, "certificate-authority-data:")[1], "\n", "", -1)
-			n++
-		}
-	}
-	f.Close()
-	if n != 4 {
-		return nil, errors.New("kubeconfig file error")
-	}
-	return config, nil
+	return config.ToConfig("")
 }
 
 func HTTPClientFor(config *Config) (*http.Client, error) {
@@ -81,42 +74,121 @@ func HTTPClientFor(config *Config) (*http.Client, error) {
 	if err != nil {
 		return nil, err
 	}
-	transport := http.Transport{
+	transport := newTransport(tlsConfig)
+	if len(config.ProxyURL) != 0 {
+		if transport.Proxy, err = proxyFor(config.ProxyURL); err != nil {
+			return nil, err
+		}
+	}
+
+	roundTripper, err := authRoundTripperFor(config, transport)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: roundTripper}, nil
+}
+
+// newTLSConfig is used by the url and token constructors, the server certificate is
+// verified with caData, or with the system CAs if caData is empty, unless insecure
+func newTLSConfig(caData [][]byte, insecure bool) *tls.Config {
+	return &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: insecure,
+		RootCAs:            rootCertPool(bytes.Join(caData, []byte("\n"))),
+	}
+}
+
+func newTransport(tlsConfig *tls.Config) *http.Transport {
+	return &http.Transport{
+		// HTTPS_PROXY, HTTP_PROXY and NO_PROXY, including socks5 proxies
+		Proxy:               http.ProxyFromEnvironment,
 		TLSHandshakeTimeout: 10 * time.Second,
 		TLSClientConfig:     tlsConfig,
 		MaxIdleConnsPerHost: 25,
-		Dial: (&net.Dialer{
-			Timeout:   30 * time.Second,
-			KeepAlive: 30 * time.Second,
-		}).Dial,
+		DialContext:         newDialer().DialContext,
 	}
-	return &http.Client{Transport: &transport}, nil
 }
 
-func TLSConfigFor(config *Config) (*tls.Config, error) {
-	certificateAuthorityData, err := base64.StdEncoding.DecodeString(config.CertificateAuthorityData)
+// proxyFor returns the proxy function of http.Transport, both HTTP CONNECT and
+// SOCKS5 proxies are supported, which are used by requests and watches
+func proxyFor(proxyURL string) (func(*http.Request) (*url.URL, error), error) {
+	u, err := url.Parse(proxyURL)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("invalid proxy-url %q: %v", proxyURL, err)
 	}
-	clientCertificateData, err := base64.StdEncoding.DecodeString(config.ClientCertificateData)
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("invalid proxy-url %q, just support http, https, socks5 and socks5h", proxyURL)
+	}
+	if len(u.Host) == 0 {
+		return nil, fmt.Errorf("invalid proxy-url %q, host is missing", proxyURL)
+	}
+	return http.ProxyURL(u), nil
+}
+
+func newDialer() *net.Dialer {
+	return &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+}
+
+func TLSConfigFor(config *Config) (*tls.Config, error) {
+	certificateAuthorityData, err := config.caData()
 	if err != nil {
 		return nil, err
 	}
-	clientKeyData, err := base64.StdEncoding.DecodeString(config.ClientKeyData)
+	if config.Insecure && len(certificateAuthorityData) != 0 {
+		return nil, errors.New("specifying a root certificates file with the insecure flag is not allowed")
+	}
+	clientCertificateData, err := dataOrFile(config.ClientCertificateData, config.ClientCertificate)
 	if err != nil {
 		return nil, err
 	}
-	ca := rootCertPool(certificateAuthorityData)
-	cert, err := tls.X509KeyPair(clientCertificateData, clientKeyData)
+	clientKeyData, err := dataOrFile(config.ClientKeyData, config.ClientKey)
 	if err != nil {
 		return nil, err
 	}
-	return &tls.Config{
+
+	tlsConfig := &tls.Config{
 		MinVersion:         tls.VersionTLS12,
-		InsecureSkipVerify: false,
-		RootCAs:            ca,
-		Certificates:       []tls.Certificate{cert},
-	}, nil
+		InsecureSkipVerify: config.Insecure,
+		RootCAs:            rootCertPool(certificateAuthorityData),
+		ServerName:         config.TLSServerName,
+	}
+
+	// the client certificate may be provided by an exec plugin instead
+	if len(clientCertificateData) != 0 || len(clientKeyData) != 0 {
+		cert, err := tls.X509KeyPair(clientCertificateData, clientKeyData)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// caData returns the PEM encoded certificate authority of the server
+func (config *Config) caData() ([]byte, error) {
+	return dataOrFile(config.CertificateAuthorityData, config.CertificateAuthority)
+}
+
+// dataOrFile decodes the base64 encoded *-data field of kubeconfig,
+// or reads the file if the field is empty
+func dataOrFile(data string, file string) ([]byte, error) {
+	if len(data) != 0 {
+		return decodeData(data)
+	}
+	if len(file) != 0 {
+		return os.ReadFile(file)
+	}
+	return nil, nil
+}
+
+// decodeData decodes the base64 encoded *-data fields of kubeconfig
+func decodeData(data string) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(data)
 }
 
 // rootCertPool returns nil if caData is empty.  When passed along, this will mean "use system CAs".
@@ -0,0 +1,90 @@
+/**
+ * Copyright (2021, ) Institute of Software, Chinese Academy of Sciences
+ */
+
+package kubesys
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+/**
+ * this class is used for providing the bearer token of each request. The
+ * projected service account token is rotated by kubelet and expires after
+ * about an hour, so the token file is reloaded periodically, or when a
+ * request fails with 401
+ *
+ *      date  : 2026/10/18
+ *      since : v2.0.4
+ */
+
+// kubelet refreshes the projected token long before it expires,
+// so reloading it every minute is enough, as the official client-go does
+const tokenFileReloadPeriod = time.Minute
+
+type TokenSource interface {
+	Token() (string, error)
+}
+
+// invalidator is implemented by the token sources which can be reloaded
+// after the server rejects the token
+type invalidator interface {
+	invalidate()
+}
+
+type staticTokenSource struct {
+	token string
+}
+
+func (source *staticTokenSource) Token() (string, error) {
+	return source.token, nil
+}
+
+type fileTokenSource struct {
+	path   string
+	period time.Duration
+
+	mutex    sync.Mutex
+	token    string
+	loadTime time.Time
+}
+
+func newFileTokenSource(path string) *fileTokenSource {
+	return &fileTokenSource{path: path, period: tokenFileReloadPeriod}
+}
+
+func (source *fileTokenSource) Token() (string, error) {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+
+	if len(source.token) != 0 && time.Since(source.loadTime) < source.period {
+		return source.token, nil
+	}
+
+	data, err := os.ReadFile(source.path)
+	if err != nil {
+		if len(source.token) != 0 {
+			// keep using the old token, the file may be in the middle of an update
+			return source.token, nil
+		}
+		return "", err
+	}
+	token := strings.TrimSpace(string(data))
+	if len(token) == 0 {
+		return "", errors.New("token file " + source.path + " is empty")
+	}
+
+	source.token = token
+	source.loadTime = time.Now()
+	return source.token, nil
+}
+
+func (source *fileTokenSource) invalidate() {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+	source.loadTime = time.Time{}
+}
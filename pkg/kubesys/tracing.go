@@ -0,0 +1,114 @@
+/**
+ * Copyright (2021, ) Institute of Software, Chinese Academy of Sciences
+ */
+
+package kubesys
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+)
+
+/**
+ * this class is used for tracing the requests of a client. A Tracer starts a span
+ * around every operation including its retries, and the W3C trace context of the
+ * caller is sent as the traceparent and tracestate headers, see
+ * https://www.w3.org/TR/trace-context/
+ *
+ *      date  : 2026/10/18
+ *      since : v2.0.4
+ */
+
+// the attributes recorded on the spans
+const (
+	TraceAttributeVerb        = "kubesys.verb"
+	TraceAttributeFullKind    = "kubesys.full_kind"
+	TraceAttributeNamespace   = "kubesys.namespace"
+	TraceAttributeName        = "kubesys.name"
+	TraceAttributeSubresource = "kubesys.subresource"
+	TraceAttributeMethod      = "http.method"
+	TraceAttributeUrl         = "http.url"
+	TraceAttributeStatusCode  = "http.status_code"
+	TraceAttributeRetries     = "kubesys.retries"
+)
+
+// Tracer is implemented by adapters of tracing libraries. Start returns a context with
+// the span, whose trace context should be put by ContextWithTraceParent, so that the
+// server sees the span as the parent
+type Tracer interface {
+	Start(ctx context.Context, spanName string, attributes map[string]interface{}) (context.Context, Span)
+}
+
+type Span interface {
+	SetAttributes(attributes map[string]interface{})
+	// End is called once, err is nil if the request succeeds
+	End(err error)
+}
+
+// the version 00 of traceparent, such as 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+var traceParentPattern = regexp.MustCompile(`^[0-9a-f]{2}-[0-9a-f]{32}-[0-9a-f]{16}-[0-9a-f]{2}$`)
+
+type traceContextKey struct{}
+
+type traceContext struct {
+	traceParent string
+	traceState  string
+}
+
+// ContextWithTraceParent carries the W3C trace context, which is sent with every
+// request using the context. An invalid traceparent is not sent
+func ContextWithTraceParent(ctx context.Context, traceParent string, traceState string) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, traceContext{traceParent: traceParent, traceState: traceState})
+}
+
+// TraceParentFrom returns the W3C trace context of the context
+func TraceParentFrom(ctx context.Context) (traceParent string, traceState string) {
+	trace, _ := ctx.Value(traceContextKey{}).(traceContext)
+	return trace.traceParent, trace.traceState
+}
+
+// traceRequest starts a span for the request if the client has a tracer, and sets the trace
+// context headers. The returned function ends the span, res is nil if no response is received
+func (client *KubernetesClient) traceRequest(request *http.Request) (*http.Request, func(res *http.Response, retries int, err error)) {
+	end := func(*http.Response, int, error) {}
+
+	if client.tracer != nil {
+		info := RequestInfoFrom(request)
+		spanName := info.Verb
+		if len(info.FullKind) != 0 {
+			spanName += " " + info.FullKind
+		}
+		if len(info.Subresource) != 0 {
+			spanName += "/" + info.Subresource
+		}
+
+		ctx, span := client.tracer.Start(request.Context(), spanName, map[string]interface{}{
+			TraceAttributeVerb:        info.Verb,
+			TraceAttributeFullKind:    info.FullKind,
+			TraceAttributeNamespace:   info.Namespace,
+			TraceAttributeName:        info.Name,
+			TraceAttributeSubresource: info.Subresource,
+			TraceAttributeMethod:      request.Method,
+			TraceAttributeUrl:         request.URL.String(),
+		})
+		request = request.WithContext(ctx)
+		end = func(res *http.Response, retries int, err error) {
+			attributes := map[string]interface{}{TraceAttributeRetries: retries}
+			if res != nil {
+				attributes[TraceAttributeStatusCode] = res.StatusCode
+			}
+			span.SetAttributes(attributes)
+			span.End(err)
+		}
+	}
+
+	traceParent, traceState := TraceParentFrom(request.Context())
+	if traceParentPattern.MatchString(traceParent) {
+		request.Header.Set("traceparent", traceParent)
+		if len(traceState) != 0 {
+			request.Header.Set("tracestate", traceState)
+		}
+	}
+	return request, end
+}
@@ -6,8 +6,10 @@ package kubesys
 
 import (
 	"encoding/json"
+	"fmt"
 	"github.com/tidwall/gjson"
-	"regexp"
+	neturl "net/url"
+	"strings"
 )
 
 /**
@@ -15,21 +17,50 @@ import (
  *      date  : 2021/9/30
  */
 
-func checkedUrl(url string) string {
-	// just support https without suffix '/'
-	// this validation is not restricted
-	httpsRegExp := regexp.MustCompile("https:\\/\\/([\\w.]+\\/?)\\S*")
-	if !httpsRegExp.MatchString(url) {
-		panic("just support https without suffix '/'")
+// the url of the requests sent through a Unix domain socket
+const unixSocketUrl = "http://localhost"
+
+// parseUrl validates the url of kube-apiserver, which can be
+//   - https://host:port, or http://host:port such as kubectl proxy, and an IPv6
+//     host must be in brackets, such as https://[::1]:6443
+//   - unix:///path/to/socket, the requests are sent through the Unix domain socket
+//
+// it returns the url for building requests without the suffix '/', and the socket path if any
+func parseUrl(rawUrl string) (string, string, error) {
+	u, err := neturl.Parse(rawUrl)
+	if err != nil {
+		return "", "", fmt.Errorf("%w %q: %v", ErrInvalidUrl, rawUrl, err)
+	}
+
+	switch u.Scheme {
+	case "https", "http":
+		if len(u.Hostname()) == 0 {
+			return "", "", fmt.Errorf("%w %q, host is missing", ErrInvalidUrl, rawUrl)
+		}
+		if !strings.HasPrefix(u.Host, "[") && strings.Count(u.Host, ":") > 1 {
+			return "", "", fmt.Errorf("%w %q, IPv6 host must be in brackets, such as https://[::1]:6443", ErrInvalidUrl, rawUrl)
+		}
+		if u.User != nil || len(u.RawQuery) != 0 || len(u.Fragment) != 0 {
+			return "", "", fmt.Errorf("%w %q, user info, query and fragment are not supported", ErrInvalidUrl, rawUrl)
+		}
+		return strings.TrimRight(rawUrl, "/"), "", nil
+	case "unix":
+		if len(u.Host) != 0 || len(u.Path) == 0 {
+			return "", "", fmt.Errorf("%w %q, the socket must be an absolute path such as unix:///path/to/socket", ErrInvalidUrl, rawUrl)
+		}
+		return unixSocketUrl, u.Path, nil
+	case "":
+		return "", "", fmt.Errorf("%w %q, scheme is missing", ErrInvalidUrl, rawUrl)
+	default:
+		return "", "", fmt.Errorf("%w %q, just support https, http and unix", ErrInvalidUrl, rawUrl)
 	}
-	return url
 }
 
-func checkedToken(token string) string {
+func validateToken(token string) error {
 	if len(token) != 0 {
-		return token
+		return nil
 	}
-	panic("token is empty")
+	return fmt.Errorf("%w, token is empty", ErrInvalidToken)
 }
 
 func ToJsonObject(bytes []byte) gjson.Result {
@@ -6,7 +6,12 @@ package kubesys
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
 )
 
 /**
@@ -39,21 +44,83 @@ func NewKubernetesWatcher(client *KubernetesClient, handler WatchHandler) *Kuber
 }
 
 func (watcher *KubernetesWatcher) Watching(url string) {
-	watcherClient := NewKubernetesClientWithAnalyzer(url, watcher.Client.Token, watcher.Client.analyzer)
-	watcherClient.http = watcher.Client.http
-	req, _ := watcherClient.createRequest("GET", url, nil)
-	resp, _ := watcherClient.http.Do(req)
+	if err := watcher.WatchingWithContext(context.Background(), url); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// WatchingWithContext dispatches the events to the handler until the context is
+// cancelled, or the server closes the stream or sends an ERROR event
+func (watcher *KubernetesWatcher) WatchingWithContext(ctx context.Context, url string) error {
+	req, err := watcher.Client.createRequest(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := watcher.open(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	info := RequestInfoFrom(req)
+	defer watcher.Client.metrics.watchOpened(info.FullKind)()
+
 	reader := bufio.NewReader(resp.Body)
 	for {
-		line, _ := reader.ReadBytes('\n')
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if errors.Is(err, io.EOF) {
+				return errors.New("watch closed by the server")
+			}
+			return err
+		}
+
 		var jsonObj = make(map[string]interface{})
-		json.Unmarshal([]byte(line), &jsonObj)
+		if err := json.Unmarshal(line, &jsonObj); err != nil {
+			continue
+		}
+		obj, _ := jsonObj["object"].(map[string]interface{})
+		eventType, _ := jsonObj["type"].(string)
+		watcher.Client.metrics.watchEvent(info.FullKind, eventType)
 		if jsonObj["type"] == "ADDED" {
-			watcher.handler.DoAdded(jsonObj["object"].(map[string]interface{}))
+			watcher.handler.DoAdded(obj)
 		} else if jsonObj["type"] == "MODIFIED" {
-			watcher.handler.DoModified(jsonObj["object"].(map[string]interface{}))
+			watcher.handler.DoModified(obj)
 		} else if jsonObj["type"] == "DELETED" {
-			watcher.handler.DoDeleted(jsonObj["object"].(map[string]interface{}))
+			watcher.handler.DoDeleted(obj)
+		} else if jsonObj["type"] == "ERROR" {
+			// the object is a metav1.Status, such as 410 Gone for a too old resourceVersion
+			status, _ := json.Marshal(obj)
+			code, _ := obj["code"].(float64)
+			return newStatusError(int(code), status)
 		}
 	}
 }
+
+// open sends the watch request, the span of the tracer ends when the stream is established
+func (watcher *KubernetesWatcher) open(req *http.Request) (resp *http.Response, err error) {
+	req, endSpan := watcher.Client.traceRequest(req)
+	defer func() {
+		endSpan(resp, 0, err)
+	}()
+
+	if watcher.Client.limiter != nil {
+		if err := watcher.Client.limiter.wait(req.Context()); err != nil {
+			return nil, err
+		}
+	}
+	resp, err = watcher.Client.send(req)
+	if err != nil {
+		return nil, fmt.Errorf("request error: %w", err)
+	}
+
+	if !(resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices) {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, newStatusError(resp.StatusCode, body)
+	}
+	return resp, nil
+}